@@ -1,8 +1,10 @@
 // Package examples provides a set of code samples that show how to use the library properly.
 //
-// Currently, there are two examples available:
+// Currently, there are three examples available:
 //
 // - webhooks - basic app that can display incoming webhooks.
 //
 // - telegram - very simple example of bidirectional Telegram transport (only text messages are supported).
+//
+// - limiter-sidecar - rate limiter server which allows the transport replicas to share one rate limit.
 package examples
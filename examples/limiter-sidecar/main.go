@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// This sidecar keeps the rate limiter counters shared by all replicas of the transport.
+// The protocol has no authentication, so it listens on the loopback interface by default.
+// The replicas should use the limiter below:
+//
+//	store := v1.NewCounterClient("tcp", "127.0.0.1:7070")
+//	limiter := v1.NewSharedLimiter(store, v1.MaxRPS)
+//	limiter.Fallback = v1.NewTokensBucket(v1.MaxRPS, time.Minute, time.Minute)
+//	client := v1.New(url, token).WithLimiter(limiter)
+func main() {
+	network, addr := os.Getenv("NETWORK"), os.Getenv("ADDR")
+	if network == "" {
+		network = "tcp"
+	}
+	if addr == "" {
+		addr = "127.0.0.1:7070"
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("listening on", network, addr)
+
+	server := &v1.CounterServer{Store: v1.NewMemoryCounterStore()}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		_ = server.Close()
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Fatal(err)
+	}
+}
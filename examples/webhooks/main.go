@@ -1,102 +1,76 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
-)
 
-// H is a basic hashmap type.
-type H map[string]interface{}
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
 
 func main() {
 	addr := os.Getenv("ADDR")
 	if addr == "" {
 		addr = ":8080"
 	}
+	token := os.Getenv("WEBHOOK_TOKEN")
+	if token == "" {
+		log.Fatal("WEBHOOK_TOKEN is required")
+	}
 	log.Println("listening on", addr)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/v1/webhook", HandleWebhook)
+	// Webhooks are authenticated by the token header. Redeliveries of the handled webhooks are rejected,
+	// the failed ones are handled again.
+	verifier := &v1.WebhookVerifier{
+		Token:        token,
+		MaxClockSkew: 5 * time.Minute,
+		Nonces:       v1.NewMemoryNonceStore(),
+	}
+	mux.Handle("/api/v1/webhook", verifier.Handler(&v1.WebhookHandler{
+		OnMessageSent:    HandleSendWebhook,
+		OnMessageRead:    HandleReadWebhook,
+		OnMessageDeleted: HandleDeleteWebhook,
+		OnTemplateCreate: HandleTemplateCreate,
+		OnTemplateUpdate: HandleTemplateUpdate,
+		OnTemplateDelete: HandleTemplateDelete,
+	}))
 	err := http.ListenAndServe(addr, mux)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("listen %s err: %s", addr, err)
 	}
 }
 
-func HandleWebhook(rw http.ResponseWriter, req *http.Request) {
-	// You should authenticate the request. Usually it's done by some sort of token header.
-	var wh v1.WebhookRequest
-	if err := readJSON(req, &wh); err != nil {
-		serveError(rw, http.StatusBadRequest, err)
-		return
-	}
-
-	switch wh.Type {
-	case v1.MessageSendWebhookType:
-		HandleSendWebhook(rw, wh.MessageWebhookData())
-	case v1.MessageReadWebhookType:
-		HandleReadWebhook(rw, wh.MessageWebhookData())
-	case v1.MessageDeleteWebhookType:
-		HandleDeleteWebhook(rw, wh.MessageWebhookData())
-	case v1.TemplateCreateWebhookType:
-		HandleTemplateCreate(rw, wh.TemplateCreateWebhookData())
-	case v1.TemplateUpdateWebhookType:
-		HandleTemplateUpdate(rw, wh.TemplateUpdateWebhookData())
-	case v1.TemplateDeleteWebhookType:
-		HandleTemplateDelete(rw, wh.TemplateDeleteWebhookData())
-	default:
-		serveError(rw, http.StatusUnprocessableEntity, fmt.Errorf("unknown webhook type: %s", wh.Type))
-	}
-}
-
-func HandleSendWebhook(rw http.ResponseWriter, msg v1.MessageWebhookData) {
+func HandleSendWebhook(_ context.Context, msg v1.MessageWebhookData) (v1.TransportResponse, error) {
 	log.Printf("incoming message: %#v", msg)
-	serveJSON(rw, http.StatusOK, v1.NewSentMessageResponse(strconv.FormatInt(time.Now().UnixNano(), 10)))
+	return v1.NewSentMessageResponse(strconv.FormatInt(time.Now().UnixNano(), 10)), nil
 }
 
-func HandleReadWebhook(rw http.ResponseWriter, msg v1.MessageWebhookData) {
+func HandleReadWebhook(_ context.Context, msg v1.MessageWebhookData) error {
 	log.Printf("incoming message read status: %#v", msg)
-	serveJSON(rw, http.StatusOK, H{})
+	return nil
 }
 
-func HandleDeleteWebhook(rw http.ResponseWriter, msg v1.MessageWebhookData) {
+func HandleDeleteWebhook(_ context.Context, msg v1.MessageWebhookData) error {
 	log.Printf("incoming message removal: %#v", msg)
-	serveJSON(rw, http.StatusOK, H{})
+	return nil
 }
 
-func HandleTemplateCreate(rw http.ResponseWriter, tpl v1.TemplateCreateWebhookData) {
+func HandleTemplateCreate(
+	_ context.Context, tpl v1.TemplateCreateWebhookData) (v1.TemplateCreateWebhookResponse, error) {
 	log.Printf("new template: %#v", tpl)
-	serveJSON(rw, http.StatusOK, H{})
+	return v1.TemplateCreateWebhookResponse{Code: tpl.Name, VerificationStatus: v1.TemplateStatusPending}, nil
 }
 
-func HandleTemplateUpdate(rw http.ResponseWriter, tpl v1.TemplateUpdateWebhookData) {
+func HandleTemplateUpdate(_ context.Context, tpl v1.TemplateUpdateWebhookData) error {
 	log.Printf("updated template: %#v", tpl)
-	serveJSON(rw, http.StatusOK, H{})
+	return nil
 }
 
-func HandleTemplateDelete(rw http.ResponseWriter, tpl v1.TemplateDeleteWebhookData) {
+func HandleTemplateDelete(_ context.Context, tpl v1.TemplateDeleteWebhookData) error {
 	log.Printf("template removal: %#v", tpl)
-	serveJSON(rw, http.StatusOK, H{})
-}
-
-func readJSON(req *http.Request, out interface{}) error {
-	defer func() { _ = req.Body.Close() }()
-	return json.NewDecoder(req.Body).Decode(out)
-}
-
-func serveJSON(rw http.ResponseWriter, st int, data interface{}) {
-	resp, _ := json.Marshal(data)
-	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
-	rw.WriteHeader(st)
-	_, _ = fmt.Fprintln(rw, string(resp))
-}
-
-func serveError(rw http.ResponseWriter, st int, err error) {
-	serveJSON(rw, st, H{"error": err.Error()})
+	return nil
 }
@@ -0,0 +1,454 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAsyncAttempts = 5
+
+// AsyncJob is the message_sent webhook waiting for the asynchronous delivery.
+type AsyncJob struct {
+	ID         string             `json:"id"`
+	Data       MessageWebhookData `json:"data"`
+	ReceivedAt time.Time          `json:"received_at"`
+}
+
+// AsyncQueue stores the jobs of the AsyncSender.
+type AsyncQueue interface {
+	// Push adds the job to the queue. Jobs with the ID which is already in the queue are ignored.
+	Push(ctx context.Context, job AsyncJob) error
+	// Pop blocks until the job is available or the context is done. The job stays in the queue until Done is called.
+	Pop(ctx context.Context) (AsyncJob, error)
+	// Done removes the processed job from the queue.
+	Done(ctx context.Context, job AsyncJob) error
+	// Release returns the popped job which was interrupted by the shutdown to the head of the queue.
+	Release(ctx context.Context, job AsyncJob) error
+}
+
+// AsyncSender delivers the messages from the message_sent webhooks in the background and confirms the delivery
+// with AckMessage. Use Enqueue as the WebhookHandler.OnMessageSentAsync callback and call Run to start the workers:
+//
+//	sender := &AsyncSender{
+//		Client: client,
+//		Send: func(ctx context.Context, data MessageWebhookData) (string, error) {
+//			return messenger.Send(ctx, data.ExternalChatID, data.Content)
+//		},
+//	}
+//	go sender.Run(ctx)
+//	http.Handle("/webhook", &WebhookHandler{OnMessageSentAsync: sender.Enqueue})
+//
+// Failed deliveries are retried according to the Retry policy. If Send returns *MessageSentError the message
+// is not retried and the error is sent to MessageGateway as is. Jobs interrupted by the shutdown stay in the queue,
+// so they survive the restart if the queue is persistent (see FileAsyncQueue). Such messages may be sent twice.
+type AsyncSender struct {
+	// Client is used to acknowledge the messages.
+	Client Client
+	// Send delivers the message and returns its external ID.
+	Send func(ctx context.Context, data MessageWebhookData) (string, error)
+	// Queue defaults to MemoryAsyncQueue.
+	Queue AsyncQueue
+	// Workers is the number of concurrent deliveries. Defaults to 1.
+	Workers int
+	// Retry is used both for the deliveries and the acknowledgements. Defaults to the BackoffRetryPolicy
+	// with 5 attempts which retries all errors.
+	Retry RetryPolicy
+	// OnError is called if the job failed. It's optional.
+	OnError func(job AsyncJob, err error)
+
+	once sync.Once
+}
+
+// Enqueue adds the message to the queue and returns the response with the Async flag.
+func (s *AsyncSender) Enqueue(ctx context.Context, data MessageWebhookData) (WebhookMessageSentResponse, error) {
+	s.init()
+
+	job := AsyncJob{ID: strconv.FormatInt(data.ID, 10), Data: data, ReceivedAt: time.Now()}
+	if err := s.Queue.Push(ctx, job); err != nil {
+		return WebhookMessageSentResponse{}, err
+	}
+
+	return WebhookMessageSentResponse{Async: true}, nil
+}
+
+// Run processes the queue until the context is done.
+func (s *AsyncSender) Run(ctx context.Context) {
+	s.init()
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *AsyncSender) init() {
+	s.once.Do(func() {
+		if s.Queue == nil {
+			s.Queue = NewMemoryAsyncQueue()
+		}
+		if s.Retry == nil {
+			retry := NewBackoffRetryPolicy(defaultAsyncAttempts)
+			retry.RetryNonIdempotent = true
+			s.Retry = retry
+		}
+	})
+}
+
+func (s *AsyncSender) work(ctx context.Context) {
+	for {
+		job, err := s.Queue.Pop(ctx)
+		if err != nil {
+			return
+		}
+
+		if err := s.process(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				if err := s.Queue.Release(context.WithoutCancel(ctx), job); err != nil && s.OnError != nil {
+					s.OnError(job, err)
+				}
+				return
+			}
+			if s.OnError != nil {
+				s.OnError(job, err)
+			}
+		}
+
+		if err := s.Queue.Done(ctx, job); err != nil && s.OnError != nil {
+			s.OnError(job, err)
+		}
+	}
+}
+
+func (s *AsyncSender) process(ctx context.Context, job AsyncJob) error {
+	ack := AckMessageRequest{
+		Channel: job.Data.ChannelID,
+		Message: &AckMessageRequestMessage{ID: &job.Data.ID},
+	}
+
+	externalID, sendErr := s.send(ctx, job)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var sentErr *MessageSentError
+	switch {
+	case sendErr == nil:
+		ack.ExternalMessageID = externalID
+	case errors.As(sendErr, &sentErr):
+		ack.Error = sentErr
+	default:
+		ack.Error = &MessageSentError{Code: GeneralError, Message: sendErr.Error()}
+	}
+
+	for attempt := 1; ; attempt++ {
+		_, err := s.Client.AckMessageContext(ctx, ack)
+		if err == nil {
+			return sendErr
+		}
+
+		retry := RetryAttempt{Number: attempt, Method: http.MethodPost, Route: "/messages/ack"}
+		if clientErr := AsClientError(err); clientErr != nil {
+			retry.StatusCode = clientErr.StatusCode
+			retry.Err = clientErr.BaseError
+		}
+
+		delay, ok := s.Retry.NextRetry(retry)
+		if !ok {
+			return errors.Join(sendErr, err)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *AsyncSender) send(ctx context.Context, job AsyncJob) (string, error) {
+	for attempt := 1; ; attempt++ {
+		externalID, err := s.Send(ctx, job.Data)
+		var sentErr *MessageSentError
+		if err == nil || errors.As(err, &sentErr) {
+			return externalID, err
+		}
+
+		delay, ok := s.Retry.NextRetry(RetryAttempt{Number: attempt, Err: err})
+		if !ok {
+			return "", err
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+}
+
+// jobList is the in-memory FIFO list of the jobs.
+type jobList struct {
+	mu     sync.Mutex
+	jobs   []AsyncJob
+	ids    map[string]struct{}
+	notify chan struct{}
+}
+
+func newJobList() jobList {
+	return jobList{ids: map[string]struct{}{}, notify: make(chan struct{}, 1)}
+}
+
+func (l *jobList) has(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.ids[id]
+	return ok
+}
+
+func (l *jobList) push(job AsyncJob) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.ids[job.ID]; ok {
+		return false
+	}
+
+	l.ids[job.ID] = struct{}{}
+	l.jobs = append(l.jobs, job)
+	l.signal()
+	return true
+}
+
+func (l *jobList) pop(ctx context.Context) (AsyncJob, error) {
+	for {
+		l.mu.Lock()
+		if len(l.jobs) > 0 {
+			job := l.jobs[0]
+			l.jobs = slices.Delete(l.jobs, 0, 1)
+			if len(l.jobs) > 0 {
+				l.signal()
+			}
+			l.mu.Unlock()
+			return job, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-l.notify:
+		case <-ctx.Done():
+			return AsyncJob{}, ctx.Err()
+		}
+	}
+}
+
+// release returns the popped job to the head of the list.
+func (l *jobList) release(job AsyncJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ids[job.ID] = struct{}{}
+	l.jobs = slices.Insert(l.jobs, 0, job)
+	l.signal()
+}
+
+func (l *jobList) done(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.ids, id)
+}
+
+// signal wakes up one of the waiting pop calls. The caller must hold the lock.
+func (l *jobList) signal() {
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+// MemoryAsyncQueue is the in-memory AsyncQueue. Its jobs are lost on restart.
+type MemoryAsyncQueue struct {
+	list jobList
+}
+
+// NewMemoryAsyncQueue returns the new MemoryAsyncQueue.
+func NewMemoryAsyncQueue() *MemoryAsyncQueue {
+	return &MemoryAsyncQueue{list: newJobList()}
+}
+
+// Push adds the job to the queue.
+func (q *MemoryAsyncQueue) Push(_ context.Context, job AsyncJob) error {
+	q.list.push(job)
+	return nil
+}
+
+// Pop returns the next job.
+func (q *MemoryAsyncQueue) Pop(ctx context.Context) (AsyncJob, error) {
+	return q.list.pop(ctx)
+}
+
+// Done removes the job from the queue.
+func (q *MemoryAsyncQueue) Done(_ context.Context, job AsyncJob) error {
+	q.list.done(job.ID)
+	return nil
+}
+
+// Release returns the job to the queue.
+func (q *MemoryAsyncQueue) Release(_ context.Context, job AsyncJob) error {
+	q.list.release(job)
+	return nil
+}
+
+// FileAsyncQueue is the AsyncQueue which keeps every job in a separate file in the directory.
+// Unfinished jobs are loaded from the directory when the queue is created.
+type FileAsyncQueue struct {
+	dir  string
+	list jobList
+}
+
+// NewFileAsyncQueue returns the new FileAsyncQueue. The directory is created if it doesn't exist.
+func NewFileAsyncQueue(dir string) (*FileAsyncQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []AsyncJob
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var job AsyncJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	slices.SortStableFunc(jobs, func(a, b AsyncJob) int {
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+
+	q := &FileAsyncQueue{dir: dir, list: newJobList()}
+	for _, job := range jobs {
+		q.list.push(job)
+	}
+
+	return q, nil
+}
+
+// Push stores the job in the file and adds it to the queue.
+func (q *FileAsyncQueue) Push(_ context.Context, job AsyncJob) error {
+	if q.list.has(job.ID) {
+		return nil
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(q.dir, q.path(job.ID), data); err != nil {
+		return err
+	}
+
+	q.list.push(job)
+	return nil
+}
+
+// Pop returns the next job.
+func (q *FileAsyncQueue) Pop(ctx context.Context) (AsyncJob, error) {
+	return q.list.pop(ctx)
+}
+
+// Done removes the job file and the job.
+func (q *FileAsyncQueue) Done(_ context.Context, job AsyncJob) error {
+	err := os.Remove(q.path(job.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+
+	q.list.done(job.ID)
+	return err
+}
+
+// Release returns the job to the queue. Its file is kept.
+func (q *FileAsyncQueue) Release(_ context.Context, job AsyncJob) error {
+	q.list.release(job)
+	return nil
+}
+
+func (q *FileAsyncQueue) path(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(q.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// writeFileAtomic writes the data to the temporary file in the directory and renames it to the path.
+// Both the file and the directory are synced, so the file survives the crash once the function returns.
+func writeFileAtomic(dir, path string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entries, e.g. the renamed files. Directories can't be synced on Windows.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
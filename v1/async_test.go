@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ackRecorder struct {
+	Client
+	mu   sync.Mutex
+	acks []AckMessageRequest
+	errs []error
+	done chan struct{}
+}
+
+func newAckRecorder(errs ...error) *ackRecorder {
+	return &ackRecorder{errs: errs, done: make(chan struct{}, 10)}
+}
+
+func (r *ackRecorder) AckMessageContext(_ context.Context, request AckMessageRequest) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return http.StatusServiceUnavailable, err
+	}
+
+	r.acks = append(r.acks, request)
+	r.done <- struct{}{}
+	return http.StatusOK, nil
+}
+
+func (r *ackRecorder) wait(t *testing.T) AckMessageRequest {
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("message was not acknowledged")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.acks[len(r.acks)-1]
+}
+
+func runAsyncSender(t *testing.T, sender *AsyncSender) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sender.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestAsyncSender_Webhook(t *testing.T) {
+	client := newAckRecorder()
+	sender := &AsyncSender{
+		Client: client,
+		Send: func(_ context.Context, data MessageWebhookData) (string, error) {
+			return "external-" + data.Content, nil
+		},
+	}
+	handler := &WebhookHandler{OnMessageSentAsync: sender.Enqueue}
+
+	rec := serveWebhook(handler, http.MethodPost, `{"type":"message_sent","data":{"id":10,"channel_id":1,"content":"1"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"external_message_id":"","async":true}`, rec.Body.String())
+
+	runAsyncSender(t, sender)
+	ack := client.wait(t)
+	assert.Equal(t, "external-1", ack.ExternalMessageID)
+	assert.Equal(t, uint64(1), ack.Channel)
+	assert.Equal(t, int64(10), *ack.Message.ID)
+	assert.Nil(t, ack.Error)
+}
+
+func TestAsyncSender_Retries(t *testing.T) {
+	attempts := 0
+	client := newAckRecorder(&HTTPClientError{StatusCode: http.StatusServiceUnavailable})
+	sender := &AsyncSender{
+		Client: client,
+		Send: func(context.Context, MessageWebhookData) (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", errors.New("connection reset")
+			}
+			return "external", nil
+		},
+		Retry: &BackoffRetryPolicy{MaxAttempts: 3, RetryNonIdempotent: true},
+	}
+	runAsyncSender(t, sender)
+
+	_, err := sender.Enqueue(context.Background(), MessageWebhookData{ID: 1})
+	require.NoError(t, err)
+
+	ack := client.wait(t)
+	assert.Equal(t, "external", ack.ExternalMessageID)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestAsyncSender_Errors(t *testing.T) {
+	client := newAckRecorder()
+	failed := make(chan AsyncJob, 2)
+	sender := &AsyncSender{
+		Client: client,
+		Send: func(_ context.Context, data MessageWebhookData) (string, error) {
+			if data.ID == 1 {
+				return "", &MessageSentError{Code: CustomerNotExistsError, Message: "customer is blocked"}
+			}
+			return "", errors.New("messenger is down")
+		},
+		Retry:   &BackoffRetryPolicy{MaxAttempts: 2, RetryNonIdempotent: true},
+		OnError: func(job AsyncJob, err error) { failed <- job },
+	}
+	runAsyncSender(t, sender)
+
+	_, _ = sender.Enqueue(context.Background(), MessageWebhookData{ID: 1})
+	ack := client.wait(t)
+	assert.Equal(t, &MessageSentError{Code: CustomerNotExistsError, Message: "customer is blocked"}, ack.Error)
+	assert.Equal(t, "1", (<-failed).ID)
+
+	_, _ = sender.Enqueue(context.Background(), MessageWebhookData{ID: 2})
+	ack = client.wait(t)
+	assert.Equal(t, &MessageSentError{Code: GeneralError, Message: "messenger is down"}, ack.Error)
+	assert.Equal(t, "2", (<-failed).ID)
+}
+
+func TestAsyncSender_ShutdownReleasesJob(t *testing.T) {
+	client := newAckRecorder()
+	started := make(chan struct{})
+	interrupt := true
+	sender := &AsyncSender{
+		Client: client,
+		Send: func(ctx context.Context, _ MessageWebhookData) (string, error) {
+			if interrupt {
+				close(started)
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			return "external", nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sender.Run(ctx)
+		close(done)
+	}()
+
+	_, err := sender.Enqueue(context.Background(), MessageWebhookData{ID: 1})
+	require.NoError(t, err)
+	<-started
+	cancel()
+	<-done
+
+	interrupt = false
+	_, err = sender.Enqueue(context.Background(), MessageWebhookData{ID: 1})
+	require.NoError(t, err)
+	runAsyncSender(t, sender)
+
+	ack := client.wait(t)
+	assert.Equal(t, "external", ack.ExternalMessageID)
+	select {
+	case <-client.done:
+		t.Fatal("released job must be delivered once")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestFileAsyncQueue(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "queue")
+	queue, err := NewFileAsyncQueue(dir)
+	require.NoError(t, err)
+
+	first := AsyncJob{ID: "1", Data: MessageWebhookData{ID: 1}, ReceivedAt: time.Unix(1, 0)}
+	second := AsyncJob{ID: "2", Data: MessageWebhookData{ID: 2}, ReceivedAt: time.Unix(2, 0)}
+	require.NoError(t, queue.Push(ctx, first))
+	require.NoError(t, queue.Push(ctx, second))
+	require.NoError(t, queue.Push(ctx, first))
+
+	job, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1", job.ID)
+	require.NoError(t, queue.Done(ctx, job))
+
+	reopened, err := NewFileAsyncQueue(dir)
+	require.NoError(t, err)
+	job, err = reopened.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2", job.ID)
+	assert.Equal(t, int64(2), job.Data.ID)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = reopened.Pop(timeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
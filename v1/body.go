@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// requestBody is a request body which can be read more than once. Seekable readers are rewound
+// for every read, other readers are buffered in memory.
+type requestBody struct {
+	data   []byte
+	seeker io.ReadSeeker
+	offset int64
+	length int64
+}
+
+func newRequestBody(r io.Reader) (*requestBody, error) {
+	switch typed := r.(type) {
+	case nil:
+		return &requestBody{}, nil
+	case *bytes.Buffer:
+		return &requestBody{data: typed.Bytes()}, nil
+	case io.ReadSeeker:
+		offset, err := typed.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		end, err := typed.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		return &requestBody{seeker: typed, offset: offset, length: end - offset}, nil
+	default:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return &requestBody{data: data}, nil
+	}
+}
+
+// reader returns the body from the beginning. It can be used as http.Request.GetBody.
+func (b *requestBody) reader() (io.ReadCloser, error) {
+	if b.seeker == nil {
+		if len(b.data) == 0 {
+			return http.NoBody, nil
+		}
+		return io.NopCloser(bytes.NewReader(b.data)), nil
+	}
+
+	if _, err := b.seeker.Seek(b.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(b.seeker), nil
+}
+
+// size returns the body length.
+func (b *requestBody) size() int64 {
+	if b.seeker == nil {
+		return int64(len(b.data))
+	}
+
+	return b.length
+}
+
+// String returns buffered body contents. It is used for the debug logging.
+func (b *requestBody) String() string {
+	if b.seeker != nil {
+		return "[stream]"
+	}
+
+	return string(b.data)
+}
@@ -2,11 +2,12 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -30,26 +31,58 @@ func NewWithClient(url string, token string, client *http.Client) *MgClient {
 	}
 }
 
-// WithLogger sets the provided logger instance into the Client.
+// WithLogger sets the provided logger instance into the Client. It is not used if the structured logger
+// is set by WithSlog. The Debug output written to it has the same redaction and size limit as the bodies logged
+// by WithSlog with the default SlogConfig. Without the BasicLogger the Debug output is written to slog.Default().
 func (c *MgClient) WithLogger(logger BasicLogger) *MgClient {
 	c.logger = logger
 	return c
 }
 
 // WithLimiter sets the provided limiter instance into the Client.
+// The limiter is not closed by Close, so it can be shared between the clients.
 func (c *MgClient) WithLimiter(limiter Limiter) *MgClient {
 	c.limiter = limiter
+	c.ownsLimiter = false
 	return c
 }
 
-// writeLog writes a message to the log.
+// WithOwnedLimiter sets the provided limiter instance into the Client. The limiter will be closed by Close
+// if it implements io.Closer, so it should not be used by other clients.
+func (c *MgClient) WithOwnedLimiter(limiter Limiter) *MgClient {
+	c.limiter = limiter
+	c.ownsLimiter = true
+	return c
+}
+
+// Close releases the resources owned by the client, i.e. the limiter set by WithOwnedLimiter.
+// The client should not be used after Close.
+func (c *MgClient) Close() error {
+	if !c.ownsLimiter {
+		return nil
+	}
+
+	if closer, ok := c.limiter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WithRetryPolicy sets the policy which decides whether failed requests should be retried.
+// By default, only rate-limited requests are retried and only if the limiter is present.
+func (c *MgClient) WithRetryPolicy(policy RetryPolicy) *MgClient {
+	c.retryPolicy = policy
+	return c
+}
+
+// writeLog writes a message to the BasicLogger or to slog.Default() at the info level if it is not set.
 func (c *MgClient) writeLog(format string, v ...interface{}) {
 	if c.logger != nil {
 		c.logger.Printf(format, v...)
 		return
 	}
 
-	log.Printf(format, v...)
+	slog.Info(fmt.Sprintf(format, v...))
 }
 
 // TransportTemplates returns templates list.
@@ -65,22 +98,13 @@ func (c *MgClient) writeLog(format string, v ...interface{}) {
 //
 //	log.Printf("status: %d, response: %#v", status, data)
 func (c *MgClient) TransportTemplates() ([]Template, int, error) {
-	var resp []Template
-
-	data, status, err := c.GetRequest("/templates", []byte{})
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
-
-	if status > http.StatusCreated || status < http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+	return c.TransportTemplatesContext(context.Background())
+}
 
-	return resp, status, err
+// TransportTemplatesContext is the same as TransportTemplates but uses the provided context.
+func (c *MgClient) TransportTemplatesContext(ctx context.Context) ([]Template, int, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/templates", nil)
+	return decodeResponse[[]Template](resp, err, http.StatusOK, http.StatusCreated)
 }
 
 // ActivateTemplate activates template with provided structure.
@@ -113,18 +137,16 @@ func (c *MgClient) TransportTemplates() ([]Template, int, error) {
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) ActivateTemplate(channelID uint64, request ActivateTemplateRequest) (int, error) {
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest(fmt.Sprintf("/channels/%d/templates", channelID), bytes.NewBuffer(outgoing))
-	if err != nil {
-		return status, err
-	}
+	return c.ActivateTemplateContext(context.Background(), channelID, request)
+}
 
-	if status > http.StatusCreated || status < http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
+// ActivateTemplateContext is the same as ActivateTemplate but uses the provided context.
+func (c *MgClient) ActivateTemplateContext(
+	ctx context.Context, channelID uint64, request ActivateTemplateRequest) (int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return status, err
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/channels/%d/templates", channelID), bytes.NewBuffer(outgoing))
+	return checkResponse(resp, err, http.StatusOK, http.StatusCreated)
 }
 
 // UpdateTemplate updates existing template by its code.
@@ -153,23 +175,21 @@ func (c *MgClient) ActivateTemplate(channelID uint64, request ActivateTemplateRe
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) UpdateTemplate(channelID uint64, code string, request UpdateTemplateRequest) (int, error) {
+	return c.UpdateTemplateContext(context.Background(), channelID, code, request)
+}
+
+// UpdateTemplateContext is the same as UpdateTemplate but uses the provided context.
+func (c *MgClient) UpdateTemplateContext(
+	ctx context.Context, channelID uint64, code string, request UpdateTemplateRequest) (int, error) {
 	outgoing, _ := json.Marshal(&request)
 
 	if channelID == 0 || code == "" {
 		return 0, errors.New("`ChannelID` and `Code` cannot be blank")
 	}
 
-	data, status, err := c.PutRequest(
-		fmt.Sprintf("/channels/%d/templates/%s", channelID, url.PathEscape(code)), outgoing)
-	if err != nil {
-		return status, err
-	}
-
-	if status != http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
-
-	return status, err
+	resp, err := c.do(ctx, http.MethodPut,
+		fmt.Sprintf("/channels/%d/templates/%s", channelID, url.PathEscape(code)), bytes.NewBuffer(outgoing))
+	return checkResponse(resp, err, http.StatusOK)
 }
 
 // DeactivateTemplate deactivates the template by its code.
@@ -185,17 +205,14 @@ func (c *MgClient) UpdateTemplate(channelID uint64, code string, request UpdateT
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) DeactivateTemplate(channelID uint64, templateCode string) (int, error) {
-	data, status, err := c.DeleteRequest(
-		fmt.Sprintf("/channels/%d/templates/%s", channelID, url.PathEscape(templateCode)), []byte{})
-	if err != nil {
-		return status, err
-	}
-
-	if status > http.StatusCreated || status < http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
+	return c.DeactivateTemplateContext(context.Background(), channelID, templateCode)
+}
 
-	return status, err
+// DeactivateTemplateContext is the same as DeactivateTemplate but uses the provided context.
+func (c *MgClient) DeactivateTemplateContext(ctx context.Context, channelID uint64, templateCode string) (int, error) {
+	resp, err := c.do(ctx, http.MethodDelete,
+		fmt.Sprintf("/channels/%d/templates/%s", channelID, url.PathEscape(templateCode)), nil)
+	return checkResponse(resp, err, http.StatusOK, http.StatusCreated)
 }
 
 // TransportChannels returns channels for current transport.
@@ -213,24 +230,15 @@ func (c *MgClient) DeactivateTemplate(channelID uint64, templateCode string) (in
 //
 //	log.Printf("status: %d, channels: %#v", status, resp)
 func (c *MgClient) TransportChannels(request Channels) ([]ChannelListItem, int, error) {
-	var resp []ChannelListItem
-	var b []byte
-	outgoing, _ := query.Values(request)
-
-	data, status, err := c.GetRequest(fmt.Sprintf("/channels?%s", outgoing.Encode()), b)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.TransportChannelsContext(context.Background(), request)
+}
 
-	if status > http.StatusCreated || status < http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// TransportChannelsContext is the same as TransportChannels but uses the provided context.
+func (c *MgClient) TransportChannelsContext(ctx context.Context, request Channels) ([]ChannelListItem, int, error) {
+	outgoing, _ := query.Values(request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/channels?%s", outgoing.Encode()), nil)
+	return decodeResponse[[]ChannelListItem](resp, err, http.StatusOK, http.StatusCreated)
 }
 
 // ActivateTransportChannel activates the channel with provided settings.
@@ -308,23 +316,16 @@ func (c *MgClient) TransportChannels(request Channels) ([]ChannelListItem, int,
 //
 //	log.Printf("status: %d, channel external_id: %s", status, resp.ExternalID)
 func (c *MgClient) ActivateTransportChannel(request Channel) (ActivateResponse, int, error) {
-	var resp ActivateResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/channels", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.ActivateTransportChannelContext(context.Background(), request)
+}
 
-	if status > http.StatusCreated || status < http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// ActivateTransportChannelContext is the same as ActivateTransportChannel but uses the provided context.
+func (c *MgClient) ActivateTransportChannelContext(
+	ctx context.Context, request Channel) (ActivateResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/channels", bytes.NewBuffer(outgoing))
+	return decodeResponse[ActivateResponse](resp, err, http.StatusOK, http.StatusCreated)
 }
 
 // UpdateTransportChannel updates an existing channel with provided settings.
@@ -403,23 +404,15 @@ func (c *MgClient) ActivateTransportChannel(request Channel) (ActivateResponse,
 //
 //	log.Printf("status: %d, channel_id: %d", status, resp.ChannelID)
 func (c *MgClient) UpdateTransportChannel(request Channel) (UpdateResponse, int, error) {
-	var resp UpdateResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PutRequest(fmt.Sprintf("/channels/%d", request.ID), outgoing)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.UpdateTransportChannelContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// UpdateTransportChannelContext is the same as UpdateTransportChannel but uses the provided context.
+func (c *MgClient) UpdateTransportChannelContext(ctx context.Context, request Channel) (UpdateResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/channels/%d", request.ID), bytes.NewBuffer(outgoing))
+	return decodeResponse[UpdateResponse](resp, err, http.StatusOK)
 }
 
 // DeactivateTransportChannel deactivates the channel by its ID.
@@ -435,26 +428,13 @@ func (c *MgClient) UpdateTransportChannel(request Channel) (UpdateResponse, int,
 //
 //	log.Printf("status: %d, deactivated at: %s", status, resp.DeactivatedAt)
 func (c *MgClient) DeactivateTransportChannel(id uint64) (DeleteResponse, int, error) {
-	var resp DeleteResponse
-	var buf []byte
-
-	data, status, err := c.DeleteRequest(
-		fmt.Sprintf("/channels/%s", strconv.FormatUint(id, 10)),
-		buf,
-	)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
-
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+	return c.DeactivateTransportChannelContext(context.Background(), id)
+}
 
-	return resp, status, err
+// DeactivateTransportChannelContext is the same as DeactivateTransportChannel but uses the provided context.
+func (c *MgClient) DeactivateTransportChannelContext(ctx context.Context, id uint64) (DeleteResponse, int, error) {
+	resp, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/channels/%s", strconv.FormatUint(id, 10)), nil)
+	return decodeResponse[DeleteResponse](resp, err, http.StatusOK)
 }
 
 // Messages sends new message.
@@ -506,23 +486,15 @@ func (c *MgClient) DeactivateTransportChannel(id uint64) (DeleteResponse, int, e
 //
 //	log.Printf("status: %d, message ID: %d", status, resp.MessageID)
 func (c *MgClient) Messages(request SendData) (MessagesResponse, int, error) {
-	var resp MessagesResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/messages", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.MessagesContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// MessagesContext is the same as Messages but uses the provided context.
+func (c *MgClient) MessagesContext(ctx context.Context, request SendData) (MessagesResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages", bytes.NewBuffer(outgoing))
+	return decodeResponse[MessagesResponse](resp, err, http.StatusOK)
 }
 
 // MessagesHistory sends history message.
@@ -569,26 +541,17 @@ func (c *MgClient) Messages(request SendData) (MessagesResponse, int, error) {
 //
 //	log.Printf("status: %d, message ID: %d", status, resp.MessageID)
 func (c *MgClient) MessagesHistory(request SendHistoryMessageRequest) (MessagesResponse, int, error) {
-	var (
-		resp     MessagesResponse
-		outgoing = &bytes.Buffer{}
-	)
-	_ = json.NewEncoder(outgoing).Encode(request)
-
-	data, status, err := c.PostRequest("/messages/history", outgoing)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.MessagesHistoryContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// MessagesHistoryContext is the same as MessagesHistory but uses the provided context.
+func (c *MgClient) MessagesHistoryContext(
+	ctx context.Context, request SendHistoryMessageRequest) (MessagesResponse, int, error) {
+	var outgoing = &bytes.Buffer{}
+	_ = json.NewEncoder(outgoing).Encode(request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages/history", outgoing)
+	return decodeResponse[MessagesResponse](resp, err, http.StatusOK)
 }
 
 // AddMessageReaction adds reactions to the message.
@@ -610,19 +573,16 @@ func (c *MgClient) MessagesHistory(request SendHistoryMessageRequest) (MessagesR
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) AddMessageReaction(request ReactionRequest) (int, error) {
+	return c.AddMessageReactionContext(context.Background(), request)
+}
+
+// AddMessageReactionContext is the same as AddMessageReaction but uses the provided context.
+func (c *MgClient) AddMessageReactionContext(ctx context.Context, request ReactionRequest) (int, error) {
 	var outgoing = &bytes.Buffer{}
 	_ = json.NewEncoder(outgoing).Encode(request)
 
-	data, status, err := c.PostRequest("/messages/reaction", outgoing)
-	if err != nil {
-		return status, err
-	}
-
-	if status != http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
-
-	return status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages/reaction", outgoing)
+	return checkResponse(resp, err, http.StatusOK)
 }
 
 // DeleteMessagesReaction removes reactions to the message.
@@ -646,18 +606,15 @@ func (c *MgClient) AddMessageReaction(request ReactionRequest) (int, error) {
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) DeleteMessagesReaction(request ReactionRequest) (int, error) {
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.DeleteRequest("/messages/reaction", outgoing)
-	if err != nil {
-		return status, err
-	}
+	return c.DeleteMessagesReactionContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
+// DeleteMessagesReactionContext is the same as DeleteMessagesReaction but uses the provided context.
+func (c *MgClient) DeleteMessagesReactionContext(ctx context.Context, request ReactionRequest) (int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return status, err
+	resp, err := c.do(ctx, http.MethodDelete, "/messages/reaction", bytes.NewBuffer(outgoing))
+	return checkResponse(resp, err, http.StatusOK)
 }
 
 // UpdateMessages edits existing message. Only text messages are supported.
@@ -679,23 +636,16 @@ func (c *MgClient) DeleteMessagesReaction(request ReactionRequest) (int, error)
 //
 //	log.Printf("status: %d, message ID: %d", status, resp.MessageID)
 func (c *MgClient) UpdateMessages(request EditMessageRequest) (MessagesResponse, int, error) {
-	var resp MessagesResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PutRequest("/messages", outgoing)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.UpdateMessagesContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// UpdateMessagesContext is the same as UpdateMessages but uses the provided context.
+func (c *MgClient) UpdateMessagesContext(
+	ctx context.Context, request EditMessageRequest) (MessagesResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPut, "/messages", bytes.NewBuffer(outgoing))
+	return decodeResponse[MessagesResponse](resp, err, http.StatusOK)
 }
 
 // MarkMessageRead send message read event to MG.
@@ -716,23 +666,16 @@ func (c *MgClient) UpdateMessages(request EditMessageRequest) (MessagesResponse,
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) MarkMessageRead(request MarkMessageReadRequest) (MarkMessageReadResponse, int, error) {
-	var resp MarkMessageReadResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/messages/read", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.MarkMessageReadContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// MarkMessageReadContext is the same as MarkMessageRead but uses the provided context.
+func (c *MgClient) MarkMessageReadContext(
+	ctx context.Context, request MarkMessageReadRequest) (MarkMessageReadResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages/read", bytes.NewBuffer(outgoing))
+	return decodeResponse[MarkMessageReadResponse](resp, err, http.StatusOK)
 }
 
 // AckMessage sets success status for message or appends an error to message.
@@ -751,18 +694,15 @@ func (c *MgClient) MarkMessageRead(request MarkMessageReadRequest) (MarkMessageR
 //
 //	log.Printf("status: %d", status)
 func (c *MgClient) AckMessage(request AckMessageRequest) (int, error) {
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/messages/ack", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return status, err
-	}
+	return c.AckMessageContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return status, NewAPIClientError(data)
-	}
+// AckMessageContext is the same as AckMessage but uses the provided context.
+func (c *MgClient) AckMessageContext(ctx context.Context, request AckMessageRequest) (int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages/ack", bytes.NewBuffer(outgoing))
+	return checkResponse(resp, err, http.StatusOK)
 }
 
 // ReadUntil will mark all messages from specified timestamp as read.
@@ -782,21 +722,16 @@ func (c *MgClient) AckMessage(request AckMessageRequest) (int, error) {
 //
 //	log.Printf("status: %d, marked messages: %+v", status, resp.IDs)
 func (c *MgClient) ReadUntil(request MarkMessagesReadUntilRequest) (*MarkMessagesReadUntilResponse, int, error) {
-	outgoing, _ := json.Marshal(&request)
+	return c.ReadUntilContext(context.Background(), request)
+}
 
-	data, status, err := c.PostRequest("/messages/read_until", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return nil, status, err
-	}
-	if status != http.StatusOK {
-		return nil, status, NewAPIClientError(data)
-	}
+// ReadUntilContext is the same as ReadUntil but uses the provided context.
+func (c *MgClient) ReadUntilContext(
+	ctx context.Context, request MarkMessagesReadUntilRequest) (*MarkMessagesReadUntilResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	var resp *MarkMessagesReadUntilResponse
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return nil, status, e
-	}
-	return resp, status, nil
+	resp, err := c.do(ctx, http.MethodPost, "/messages/read_until", bytes.NewBuffer(outgoing))
+	return decodeResponse[*MarkMessagesReadUntilResponse](resp, err, http.StatusOK)
 }
 
 // DeleteMessage removes the message.
@@ -817,25 +752,15 @@ func (c *MgClient) ReadUntil(request MarkMessagesReadUntilRequest) (*MarkMessage
 //
 //	log.Printf("status: %d, message ID: %d", status, resp.MessageID)
 func (c *MgClient) DeleteMessage(request DeleteData) (*MessagesResponse, int, error) {
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.DeleteRequest(
-		"/messages",
-		outgoing,
-	)
-	if err != nil {
-		return nil, status, err
-	}
-	if status != http.StatusOK {
-		return nil, status, NewAPIClientError(data)
-	}
+	return c.DeleteMessageContext(context.Background(), request)
+}
 
-	var previousChatMessage *MessagesResponse
-	if e := json.Unmarshal(data, &previousChatMessage); e != nil {
-		return nil, status, e
-	}
+// DeleteMessageContext is the same as DeleteMessage but uses the provided context.
+func (c *MgClient) DeleteMessageContext(ctx context.Context, request DeleteData) (*MessagesResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return previousChatMessage, status, nil
+	resp, err := c.do(ctx, http.MethodDelete, "/messages", bytes.NewBuffer(outgoing))
+	return decodeResponse[*MessagesResponse](resp, err, http.StatusOK)
 }
 
 // GetFile returns file information by its ID.
@@ -851,28 +776,20 @@ func (c *MgClient) DeleteMessage(request DeleteData) (*MessagesResponse, int, er
 //
 //	log.Printf("status: %d, file URL: %s", status, resp.Url)
 func (c *MgClient) GetFile(request string) (FullFileResponse, int, error) {
-	var resp FullFileResponse
-	var b []byte
-
-	data, status, err := c.GetRequest(fmt.Sprintf("/files/%s", request), b)
-
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
-
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+	return c.GetFileContext(context.Background(), request)
+}
 
-	return resp, status, err
+// GetFileContext is the same as GetFile but uses the provided context.
+func (c *MgClient) GetFileContext(ctx context.Context, request string) (FullFileResponse, int, error) {
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/files/%s", request), nil)
+	return decodeResponse[FullFileResponse](resp, err, http.StatusOK)
 }
 
 // UploadFile uploads a file.
 //
+// If the provided reader implements io.Seeker, it will be rewound when the request is retried.
+// Otherwise, the file contents will be buffered in memory before sending.
+//
 // Example:
 //
 //	client := New("https://message-gateway.url", "cb8ccf05e38a47543ad8477d4999be73bff503ea6")
@@ -895,22 +812,13 @@ func (c *MgClient) GetFile(request string) (FullFileResponse, int, error) {
 //
 //	log.Printf("status: %d, file ID: %s", status, resp.ID)
 func (c *MgClient) UploadFile(request io.Reader) (UploadFileResponse, int, error) {
-	var resp UploadFileResponse
-
-	data, status, err := c.PostRequest("/files/upload", request)
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
-
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+	return c.UploadFileContext(context.Background(), request)
+}
 
-	return resp, status, err
+// UploadFileContext is the same as UploadFile but uses the provided context.
+func (c *MgClient) UploadFileContext(ctx context.Context, request io.Reader) (UploadFileResponse, int, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/files/upload", request)
+	return decodeResponse[UploadFileResponse](resp, err, http.StatusOK)
 }
 
 // UploadFileByURL uploads a file from provided URL.
@@ -928,23 +836,16 @@ func (c *MgClient) UploadFile(request io.Reader) (UploadFileResponse, int, error
 //
 //	log.Printf("status: %d, file ID: %s", status, resp.ID)
 func (c *MgClient) UploadFileByURL(request UploadFileByUrlRequest) (UploadFileResponse, int, error) {
-	var resp UploadFileResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/files/upload_by_url", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.UploadFileByURLContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// UploadFileByURLContext is the same as UploadFileByURL but uses the provided context.
+func (c *MgClient) UploadFileByURLContext(
+	ctx context.Context, request UploadFileByUrlRequest) (UploadFileResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/files/upload_by_url", bytes.NewBuffer(outgoing))
+	return decodeResponse[UploadFileResponse](resp, err, http.StatusOK)
 }
 
 // RestoreMessage restores deleted message.
@@ -965,23 +866,16 @@ func (c *MgClient) UploadFileByURL(request UploadFileByUrlRequest) (UploadFileRe
 //
 //	log.Printf("status: %d, message ID: %d", status, resp.MessageID)
 func (c *MgClient) RestoreMessage(request RestoreMessageRequest) (MessagesResponse, int, error) {
-	var resp MessagesResponse
-	outgoing, _ := json.Marshal(&request)
-
-	data, status, err := c.PostRequest("/messages/restore", bytes.NewBuffer(outgoing))
-	if err != nil {
-		return resp, status, err
-	}
-
-	if e := json.Unmarshal(data, &resp); e != nil {
-		return resp, status, e
-	}
+	return c.RestoreMessageContext(context.Background(), request)
+}
 
-	if status != http.StatusOK {
-		return resp, status, NewAPIClientError(data)
-	}
+// RestoreMessageContext is the same as RestoreMessage but uses the provided context.
+func (c *MgClient) RestoreMessageContext(
+	ctx context.Context, request RestoreMessageRequest) (MessagesResponse, int, error) {
+	outgoing, _ := json.Marshal(&request)
 
-	return resp, status, err
+	resp, err := c.do(ctx, http.MethodPost, "/messages/restore", bytes.NewBuffer(outgoing))
+	return decodeResponse[MessagesResponse](resp, err, http.StatusOK)
 }
 
 // MakeTimestamp returns current unix timestamp in milliseconds.
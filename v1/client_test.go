@@ -2,6 +2,7 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -354,6 +355,30 @@ func (t *MGClientTest) Test_UpdateTransportChannel() {
 	t.Assert().NotEmpty(data.UpdatedAt.String())
 }
 
+func (t *MGClientTest) Test_ListsAcceptCreatedStatus() {
+	c := t.client()
+
+	defer gock.Off()
+	t.gock().
+		Get(t.transportURL("templates")).
+		Reply(http.StatusCreated).
+		JSON([]Template{{Code: "tpl_code", ChannelID: 1}})
+	t.gock().
+		Get(t.transportURL("channels")).
+		Reply(http.StatusCreated).
+		JSON([]ChannelListItem{{ID: 1}})
+
+	templates, status, err := c.TransportTemplates()
+	t.Require().NoError(err)
+	t.Assert().Equal(http.StatusCreated, status)
+	t.Assert().Len(templates, 1)
+
+	channels, status, err := c.TransportChannels(Channels{})
+	t.Require().NoError(err)
+	t.Assert().Equal(http.StatusCreated, status)
+	t.Assert().Len(channels, 1)
+}
+
 func (t *MGClientTest) Test_TransportTemplates() {
 	c := t.client()
 
@@ -1202,3 +1227,147 @@ func (t *MGClientTest) Test_RestoreMessage() {
 	t.Assert().NotEmpty(data.Time.String())
 	t.Assert().Equal(1, data.MessageID)
 }
+
+func (t *MGClientTest) Test_MessagesContext_Canceled() {
+	c := t.client()
+
+	defer gock.Off()
+	t.gock().
+		Post(t.transportURL("messages")).
+		Reply(http.StatusOK).
+		JSON(MessagesResponse{MessageID: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, status, err := c.MessagesContext(ctx, SendData{Channel: 1, ExternalChatID: "chat"})
+	t.Require().Error(err)
+	t.Assert().Equal(0, status)
+	t.Assert().ErrorIs(err, context.Canceled)
+	t.Assert().True(gock.IsPending())
+}
+
+func (t *MGClientTest) Test_MessagesContext_LimiterWaitCanceled() {
+	c := t.client()
+	c.WithLimiter(&blockingLimiter{release: make(chan struct{})})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := c.MessagesContext(ctx, SendData{Channel: 1, ExternalChatID: "chat"})
+	t.Require().Error(err)
+	t.Assert().ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (t *MGClientTest) Test_MessagesContext_ContextLimiterWaitCanceled() {
+	c := t.client()
+	limiter := &TokensBucket{
+		maxRPS:     1,
+		shards:     []*tokenShard{{tokens: map[string]*token{}}},
+		shardCount: 1,
+		sleep:      realSleeper{},
+	}
+	c.WithLimiter(limiter)
+	t.Require().True(limiter.Allow(c.Token))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := c.MessagesContext(ctx, SendData{Channel: 1, ExternalChatID: "chat"})
+	t.Require().Error(err)
+	t.Assert().ErrorIs(err, context.DeadlineExceeded)
+	t.Assert().Less(time.Since(start), 500*time.Millisecond)
+	t.Assert().False(limiter.Allow(c.Token), "cancelled request should not consume the next window")
+}
+
+func (t *MGClientTest) Test_GetFileContext() {
+	c := t.client()
+
+	defer gock.Off()
+	t.gock().
+		Get(t.transportURL("files/file_id")).
+		Reply(http.StatusOK).
+		JSON(FullFileResponse{ID: "file_id", Type: "image"})
+
+	data, status, err := c.GetFileContext(context.Background(), "file_id")
+	t.Require().NoError(err)
+	t.Assert().Equal(http.StatusOK, status)
+	t.Assert().Equal("file_id", data.ID)
+}
+
+type blockingLimiter struct {
+	release chan struct{}
+}
+
+func (l *blockingLimiter) Obtain(string) {
+	<-l.release
+}
+
+func (t *MGClientTest) Test_RetryPolicy() {
+	c := t.client()
+	policy := NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	c.WithRetryPolicy(policy)
+
+	defer gock.Off()
+	t.gock().
+		Get(t.transportURL("channels")).
+		Reply(http.StatusServiceUnavailable)
+	t.gock().
+		Get(t.transportURL("channels")).
+		Reply(http.StatusBadGateway)
+	t.gock().
+		Get(t.transportURL("channels")).
+		Reply(http.StatusOK).
+		JSON([]ChannelListItem{{ID: 1}})
+
+	data, status, err := c.TransportChannels(Channels{})
+	t.Require().NoError(err)
+	t.Assert().Equal(http.StatusOK, status)
+	t.Assert().Len(data, 1)
+	t.Assert().True(gock.IsDone())
+}
+
+func (t *MGClientTest) Test_RetryPolicy_NonIdempotent() {
+	c := t.client()
+	policy := NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	c.WithRetryPolicy(policy)
+
+	defer gock.Off()
+	t.gock().
+		Post(t.transportURL("messages")).
+		Reply(http.StatusBadGateway)
+	t.gock().
+		Post(t.transportURL("messages")).
+		Reply(http.StatusOK).
+		JSON(MessagesResponse{MessageID: 1})
+
+	_, status, err := c.Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	t.Require().Error(err)
+	t.Assert().Equal(http.StatusBadGateway, status)
+	t.Assert().True(gock.IsPending())
+}
+
+func (t *MGClientTest) Test_APIErrorDetails() {
+	c := t.client()
+
+	defer gock.Off()
+	t.gock().
+		Put(t.transportURL("messages")).
+		Reply(http.StatusNotFound).
+		JSON(`{"errors": ["Message not found", "Channel not found"]}`)
+
+	_, status, err := c.UpdateMessages(EditMessageRequest{Channel: 1})
+	t.Assert().Equal(http.StatusNotFound, status)
+
+	clientErr := AsClientError(err)
+	t.Require().NotNil(clientErr)
+	t.Assert().True(clientErr.IsNotFound())
+	t.Assert().Equal(http.StatusNotFound, clientErr.StatusCode)
+	t.Assert().Equal(http.MethodPut, clientErr.Method)
+	t.Assert().Equal("/messages", clientErr.Route)
+	t.Assert().Equal([]string{"Message not found", "Channel not found"}, clientErr.Errors)
+	t.Assert().NotNil(clientErr.Response)
+}
@@ -0,0 +1,316 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The counter protocol is line based. The request is "ADD <key> <delta> <ttl in milliseconds>\n",
+// the response is either "<value>\n" or "ERR <message>\n". Keys must not contain whitespace.
+const (
+	counterCommandAdd     = "ADD"
+	counterResponseError  = "ERR"
+	counterRequestFields  = 4
+	defaultCounterIdleMax = 16
+	maxCounterLineLength  = 4096
+)
+
+// ErrCounterClientClosed is returned by the CounterClient after Close.
+var ErrCounterClientClosed = errors.New("counter client is closed")
+
+// CounterServer serves the CounterStore over TCP or Unix socket, so it can be used as a sidecar
+// by all replicas of the transport. Use CounterClient as the CounterStore of the SharedLimiter
+// to connect to it.
+//
+// The protocol has no authentication or encryption: anyone who can connect to the server can read and
+// change the counters. Listen on a Unix socket or on the loopback interface, never on a public address.
+//
+// Example:
+//
+//	listener, err := net.Listen("unix", "/var/run/mg-limiter.sock")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	server := &v1.CounterServer{Store: v1.NewMemoryCounterStore()}
+//	log.Fatal(server.Serve(listener))
+type CounterServer struct {
+	// Store keeps the counters. MemoryCounterStore is used if it's nil.
+	Store CounterStore
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Serve accepts the connections on the listener until the server is closed.
+func (s *CounterServer) Serve(listener net.Listener) error {
+	if !s.track(listener) {
+		return net.ErrClosed
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+			return err
+		}
+
+		if !s.trackConn(conn) {
+			_ = conn.Close()
+			return net.ErrClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners and closes the active connections.
+func (s *CounterServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var errs []error
+	for listener := range s.listeners {
+		errs = append(errs, listener.Close())
+	}
+	for conn := range s.conns {
+		errs = append(errs, conn.Close())
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *CounterServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, maxCounterLineLength), maxCounterLineLength)
+	writer := bufio.NewWriter(conn)
+
+	for scanner.Scan() {
+		value, err := s.handle(scanner.Text())
+		if err != nil {
+			_, _ = fmt.Fprintf(writer, "%s %s\n", counterResponseError, strings.ReplaceAll(err.Error(), "\n", " "))
+		} else {
+			_, _ = fmt.Fprintf(writer, "%d\n", value)
+		}
+
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *CounterServer) handle(line string) (int64, error) {
+	fields := strings.Fields(line)
+	if len(fields) != counterRequestFields || fields[0] != counterCommandAdd {
+		return 0, fmt.Errorf("invalid request: %q", line)
+	}
+
+	delta, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delta: %w", err)
+	}
+
+	ttl, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid ttl: %q", fields[3])
+	}
+
+	return s.store().Add(context.Background(), fields[1], delta, time.Duration(ttl)*time.Millisecond)
+}
+
+func (s *CounterServer) store() CounterStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Store == nil {
+		s.Store = NewMemoryCounterStore()
+	}
+	return s.Store
+}
+
+func (s *CounterServer) track(listener net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[listener] = struct{}{}
+	return true
+}
+
+func (s *CounterServer) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *CounterServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// CounterClient is the CounterStore which sends the requests to the CounterServer.
+// It keeps up to MaxIdle open connections and is safe for concurrent use.
+type CounterClient struct {
+	// Network is "tcp" or "unix".
+	Network string
+	// Address of the CounterServer.
+	Address string
+	// MaxIdle is the maximum number of idle connections. Default is 16.
+	MaxIdle int
+
+	mu     sync.Mutex
+	idle   []*counterConn
+	closed bool
+	dialer net.Dialer
+}
+
+type counterConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewCounterClient creates the CounterClient for the server at the provided address.
+func NewCounterClient(network, address string) *CounterClient {
+	return &CounterClient{Network: network, Address: address}
+}
+
+// Add implements CounterStore.
+func (c *CounterClient) Add(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if key == "" || strings.ContainsAny(key, " \t\r\n") {
+		return 0, fmt.Errorf("invalid counter key: %q", key)
+	}
+
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := c.roundTrip(ctx, conn, key, delta, ttl)
+	if err != nil {
+		_ = conn.Close()
+		return 0, err
+	}
+
+	c.put(conn)
+	return value, nil
+}
+
+// Close closes the idle connections. The client cannot be used after Close.
+func (c *CounterClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	var errs []error
+	for _, conn := range c.idle {
+		errs = append(errs, conn.Close())
+	}
+	c.idle = nil
+	return errors.Join(errs...)
+}
+
+func (c *CounterClient) roundTrip(
+	ctx context.Context, conn *counterConn, key string, delta int64, ttl time.Duration) (int64, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultSharedStoreTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s %s %d %d\n", counterCommandAdd, key, delta, counterTTL(ttl)); err != nil {
+		return 0, err
+	}
+
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	if msg, isErr := strings.CutPrefix(line, counterResponseError+" "); isErr {
+		return 0, fmt.Errorf("counter server: %s", msg)
+	}
+
+	return strconv.ParseInt(line, 10, 64)
+}
+
+func (c *CounterClient) conn(ctx context.Context) (*counterConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrCounterClientClosed
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	conn, err := c.dialer.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &counterConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *CounterClient) put(conn *counterConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maxIdle := c.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = defaultCounterIdleMax
+	}
+
+	if c.closed || len(c.idle) >= maxIdle {
+		_ = conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// counterTTL returns the ttl in milliseconds. It's rounded up, so the counters which should live less than
+// a millisecond are not expired at once.
+func counterTTL(ttl time.Duration) int64 {
+	return int64((ttl + time.Millisecond - 1) / time.Millisecond)
+}
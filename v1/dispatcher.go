@@ -0,0 +1,270 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+const defaultDispatcherQueueSize = 1024
+
+// ErrDispatcherClosed is returned if the operation is submitted after the Dispatcher was closed.
+var ErrDispatcherClosed = errors.New("dispatcher is closed")
+
+// ErrOperationPanicked is returned by Do if the operation has panicked.
+var ErrOperationPanicked = errors.New("chat operation panicked")
+
+// ChatOperation is the operation which is executed by the Dispatcher.
+type ChatOperation func(ctx context.Context, client Client) error
+
+// DispatcherConfig configures the Dispatcher.
+type DispatcherConfig struct {
+	// Workers is the maximum number of chats which are served concurrently. Defaults to the number of CPUs.
+	Workers int
+	// QueueSize is the maximum number of the pending operations. Submit blocks when the queue is full.
+	// Defaults to 1024.
+	QueueSize int
+}
+
+type dispatchTask struct {
+	ctx  context.Context
+	op   ChatOperation
+	done chan error
+}
+
+type dispatchChat struct {
+	tasks []*dispatchTask
+}
+
+// Dispatcher executes the operations of the same chat (channel and external chat ID) one by one in the order
+// they were submitted while the operations of different chats are executed concurrently. It allows sending
+// the message and then editing or deleting it from different goroutines without reordering them at MG.
+//
+// The number of the concurrent requests is bounded by the workers count, the requests themselves are throttled
+// by the client limiter. Submit blocks when the queue is full. Close stops accepting the new operations and waits
+// for the pending ones.
+//
+// Example:
+//
+//	dispatcher := NewDispatcher(client, DispatcherConfig{Workers: 8})
+//	defer dispatcher.Close(context.Background())
+//
+//	resp, _, err := dispatcher.Messages(ctx, SendData{...})
+//	_, _, err = dispatcher.UpdateMessages(ctx, "chat_id_1", EditMessageRequest{...})
+type Dispatcher struct {
+	client Client
+	ready  chan chatKey
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	chats   map[chatKey]*dispatchChat
+	closed  bool
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+// NewDispatcher returns the new Dispatcher and starts its workers.
+func NewDispatcher(client Client, config DispatcherConfig) *Dispatcher {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultDispatcherQueueSize
+	}
+
+	d := &Dispatcher{
+		client: client,
+		ready:  make(chan chatKey, queueSize),
+		slots:  make(chan struct{}, queueSize),
+		chats:  map[chatKey]*dispatchChat{},
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	for i := 0; i < workers; i++ {
+		d.workers.Add(1)
+		go d.work()
+	}
+
+	return d
+}
+
+// Submit adds the operation to the chat queue and returns without waiting for it. The operation context
+// keeps the values of the provided context but is not canceled with it. Submit blocks while the queue is full.
+func (d *Dispatcher) Submit(ctx context.Context, channelID uint64, externalChatID string, op ChatOperation) error {
+	return d.submit(ctx, chatKey{channelID, externalChatID}, &dispatchTask{ctx: context.WithoutCancel(ctx), op: op})
+}
+
+// Do executes the operation in the chat queue and returns its result. If the context is done while
+// the operation is waiting in the queue, Do returns the context error and the operation is skipped.
+func (d *Dispatcher) Do(ctx context.Context, channelID uint64, externalChatID string, op ChatOperation) error {
+	task := &dispatchTask{ctx: ctx, op: op, done: make(chan error, 1)}
+	if err := d.submit(ctx, chatKey{channelID, externalChatID}, task); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-task.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Messages sends the message with MgClient.Messages in the chat queue.
+func (d *Dispatcher) Messages(ctx context.Context, request SendData) (MessagesResponse, int, error) {
+	var resp MessagesResponse
+	var status int
+	err := d.Do(ctx, request.Channel, request.ExternalChatID, func(ctx context.Context, client Client) (err error) {
+		resp, status, err = client.MessagesContext(ctx, request)
+		return
+	})
+	return resp, status, err
+}
+
+// UpdateMessages edits the message with MgClient.UpdateMessages in the chat queue.
+func (d *Dispatcher) UpdateMessages(
+	ctx context.Context, externalChatID string, request EditMessageRequest) (MessagesResponse, int, error) {
+	var resp MessagesResponse
+	var status int
+	err := d.Do(ctx, request.Channel, externalChatID, func(ctx context.Context, client Client) (err error) {
+		resp, status, err = client.UpdateMessagesContext(ctx, request)
+		return
+	})
+	return resp, status, err
+}
+
+// DeleteMessage deletes the message with MgClient.DeleteMessage in the chat queue.
+func (d *Dispatcher) DeleteMessage(
+	ctx context.Context, externalChatID string, request DeleteData) (*MessagesResponse, int, error) {
+	var resp *MessagesResponse
+	var status int
+	err := d.Do(ctx, request.Channel, externalChatID, func(ctx context.Context, client Client) (err error) {
+		resp, status, err = client.DeleteMessageContext(ctx, request)
+		return
+	})
+	return resp, status, err
+}
+
+// Close stops accepting the operations and waits until the pending ones are executed. If the context is done
+// before that, the running operations are canceled, the queued ones are skipped with ErrDispatcherClosed
+// and Close returns the context error.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		d.cancel()
+		<-drained
+	}
+
+	close(d.ready)
+	d.workers.Wait()
+	d.cancel()
+	return err
+}
+
+func (d *Dispatcher) submit(ctx context.Context, key chatKey, task *dispatchTask) error {
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		<-d.slots
+		return ErrDispatcherClosed
+	}
+
+	d.pending.Add(1)
+	chat, ok := d.chats[key]
+	if !ok {
+		chat = &dispatchChat{}
+		d.chats[key] = chat
+	}
+
+	chat.tasks = append(chat.tasks, task)
+	if len(chat.tasks) == 1 {
+		d.ready <- key
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+
+	for key := range d.ready {
+		d.mu.Lock()
+		task := d.chats[key].tasks[0]
+		d.mu.Unlock()
+
+		d.run(task)
+
+		d.mu.Lock()
+		chat := d.chats[key]
+		chat.tasks = chat.tasks[1:]
+		if len(chat.tasks) > 0 {
+			d.ready <- key
+		} else {
+			delete(d.chats, key)
+		}
+		d.mu.Unlock()
+
+		<-d.slots
+		d.pending.Done()
+	}
+}
+
+func (d *Dispatcher) run(task *dispatchTask) {
+	err := d.execute(task)
+	if task.done != nil {
+		task.done <- err
+	}
+}
+
+// execute calls the operation unless its context is already done or the pending operations were canceled
+// by Close. The panic of the operation is returned as the error, so the worker keeps serving the queue.
+func (d *Dispatcher) execute(task *dispatchTask) (err error) {
+	if d.ctx.Err() != nil {
+		return ErrDispatcherClosed
+	}
+	if err := task.ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(task.ctx)
+	stop := context.AfterFunc(d.ctx, cancel)
+	defer func() {
+		stop()
+		cancel()
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrOperationPanicked, r)
+		}
+	}()
+
+	return task.op(ctx, d.client)
+}
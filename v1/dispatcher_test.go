@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_OrdersChatOperations(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 4})
+
+	var mu sync.Mutex
+	order := map[string][]int{}
+	var running, maxRunning atomic.Int32
+
+	for i := 0; i < 20; i++ {
+		for chat := 0; chat < 4; chat++ {
+			chatID, i := fmt.Sprintf("chat%d", chat), i
+			err := d.Submit(context.Background(), 1, chatID, func(context.Context, Client) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				defer mu.Unlock()
+				order[chatID] = append(order[chatID], i)
+				return nil
+			})
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, d.Close(context.Background()))
+
+	for chatID, ops := range order {
+		require.Len(t, ops, 20, chatID)
+		for i, op := range ops {
+			assert.Equal(t, i, op, chatID)
+		}
+	}
+	assert.Greater(t, maxRunning.Load(), int32(1), "different chats must be served concurrently")
+}
+
+func TestDispatcher_Backpressure(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 1, QueueSize: 1})
+	release := make(chan struct{})
+	require.NoError(t, d.Submit(context.Background(), 1, "chat", func(context.Context, Client) error {
+		<-release
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := d.Submit(ctx, 1, "other", func(context.Context, Client) error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, d.Close(context.Background()))
+	assert.ErrorIs(t, d.Submit(context.Background(), 1, "chat", nil), ErrDispatcherClosed)
+}
+
+func TestDispatcher_CloseCancelsPendingOperations(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 1})
+	canceled := make(chan error, 1)
+	require.NoError(t, d.Submit(context.Background(), 1, "chat", func(ctx context.Context, _ Client) error {
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+}
+
+func TestDispatcher_CloseSkipsQueuedOperations(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 1})
+	started := make(chan struct{})
+	require.NoError(t, d.Submit(context.Background(), 1, "chat", func(ctx context.Context, _ Client) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	<-started
+
+	var called atomic.Int32
+	results := make(chan error, 5)
+	for i := 0; i < cap(results); i++ {
+		go func(chat string) {
+			results <- d.Do(context.Background(), 1, chat, func(context.Context, Client) error {
+				called.Add(1)
+				return nil
+			})
+		}(fmt.Sprint(i))
+	}
+	require.Eventually(t, func() bool { return len(d.slots) == cap(results)+1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Close(ctx), context.DeadlineExceeded)
+
+	for i := 0; i < cap(results); i++ {
+		assert.ErrorIs(t, <-results, ErrDispatcherClosed)
+	}
+	assert.Zero(t, called.Load(), "queued operations must not run after Close has timed out")
+}
+
+func TestDispatcher_Messages(t *testing.T) {
+	client := &outboxClient{}
+	d := NewDispatcher(client, DispatcherConfig{})
+	defer d.Close(context.Background())
+
+	_, _, err := d.Messages(context.Background(), sendData("chat", "hello"))
+	require.NoError(t, err)
+	_, _, err = d.UpdateMessages(context.Background(), "chat",
+		EditMessageRequest{Channel: 1, Message: EditMessageRequestMessage{Text: "edited"}})
+	require.NoError(t, err)
+	_, _, err = d.DeleteMessage(context.Background(), "chat", DeleteData{Channel: 1, Message: Message{ExternalID: "ext"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"chat:send:hello", "edit:edited", "delete:ext"}, client.recorded())
+}
+
+func TestDispatcher_DoCanceledInQueue(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 1})
+	release := make(chan struct{})
+	require.NoError(t, d.Submit(context.Background(), 1, "chat", func(context.Context, Client) error {
+		<-release
+		return nil
+	}))
+
+	var called atomic.Bool
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := d.Do(ctx, 1, "chat", func(context.Context, Client) error {
+		called.Store(true)
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, d.Close(context.Background()))
+	assert.False(t, called.Load(), "canceled operation must be skipped")
+}
+
+func TestDispatcher_OperationPanic(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{Workers: 1})
+
+	err := d.Do(context.Background(), 1, "chat", func(context.Context, Client) error {
+		panic("boom")
+	})
+	assert.ErrorIs(t, err, ErrOperationPanicked)
+	assert.ErrorContains(t, err, "boom")
+
+	require.NoError(t, d.Submit(context.Background(), 1, "chat", func(context.Context, Client) error {
+		panic("boom")
+	}))
+	assert.NoError(t, d.Do(context.Background(), 1, "chat", func(context.Context, Client) error { return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, d.Close(ctx))
+}
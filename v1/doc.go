@@ -4,6 +4,9 @@
 // The package github.com/retailcrm/mg-transport-api-client-go/examples contains some examples on how to
 // use this library properly.
 //
+// Every API method has a counterpart with the Context suffix (e.g. MessagesContext for Messages) which accepts
+// context.Context. The context is used for the HTTP request itself, for the rate limiter waits and for the retries.
+//
 // Basic usage example:
 //
 //	client := New("https://message-gateway.url", "cb8ccf05e38a47543ad8477d4999be73bff503ea6")
@@ -44,6 +47,9 @@
 //			if clientErr.BaseError != nil {
 //				log.Fatalf("cannot perform the request: %s", clientErr.BaseError)
 //			}
+//			if clientErr.IsValidation() {
+//				log.Fatalf("MG rejected the message: %v", clientErr.Errors)
+//			}
 //			if clientErr.ErrorMsg != "" {
 //				log.Fatalf("MG error: %s", clientErr.ErrorMsg)
 //			}
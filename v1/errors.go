@@ -2,6 +2,7 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,16 +14,32 @@ var defaultErrorMessage = "http client error"
 var internalServerError = "internal server error"
 var marshalError = "cannot unmarshal response body"
 
+// ErrResponseTruncated is returned if the successful response body is larger than LimitResponse.
+var ErrResponseTruncated = errors.New("response body exceeds the size limit")
+
 // MGErrors contains a list of errors as sent by MessageGateway.
 type MGErrors struct {
 	Errors []string
 }
 
 // HTTPClientError is a common error type used in the client.
+//
+// Network errors are stored in the BaseError field. API errors contain the response status, the request method
+// and route, all error messages returned by MG and the raw response body.
 type HTTPClientError struct {
 	ErrorMsg  string
 	BaseError error
 	Response  io.Reader
+	// StatusCode of the response. It will be 0 if the request has failed with the network error.
+	StatusCode int
+	// Method of the failed request.
+	Method string
+	// Route template of the failed request, e.g. "/channels/{id}".
+	Route string
+	// Errors contains all error messages from the response.
+	Errors []string
+	// Body is the raw response body.
+	Body []byte
 }
 
 // Unwrap returns underlying error. Its presence usually indicates a problem with the network.
@@ -50,6 +67,25 @@ func NewCriticalHTTPError(err error) error {
 
 // NewAPIClientError wraps MG error.
 func NewAPIClientError(responseBody []byte) error {
+	return parseAPIError(responseBody)
+}
+
+// newAPIError returns an error for the unsuccessful response.
+func newAPIError(req *APIRequest, resp *APIResponse) error {
+	err := parseAPIError(resp.Body)
+	err.StatusCode = resp.StatusCode
+	err.Method = req.Method
+	err.Route = req.Route
+	err.Body = resp.Body
+
+	if len(resp.Body) > 0 {
+		err.Response = bytes.NewBuffer(resp.Body)
+	}
+
+	return err
+}
+
+func parseAPIError(responseBody []byte) *HTTPClientError {
 	var data MGErrors
 	var message string
 
@@ -65,7 +101,7 @@ func NewAPIClientError(responseBody []byte) error {
 		}
 	}
 
-	return &HTTPClientError{ErrorMsg: message}
+	return &HTTPClientError{ErrorMsg: message, Errors: data.Errors}
 }
 
 // NewServerError wraps an unexpected API error (e.g. 5xx).
@@ -83,6 +119,55 @@ func NewServerError(response *http.Response) error {
 	return err
 }
 
+// IsNotFound returns true if MG responded with 404 Not Found.
+func (err *HTTPClientError) IsNotFound() bool {
+	return err != nil && err.StatusCode == http.StatusNotFound
+}
+
+// IsRateLimited returns true if MG responded with 429 Too Many Requests.
+func (err *HTTPClientError) IsRateLimited() bool {
+	return err != nil && err.StatusCode == http.StatusTooManyRequests
+}
+
+// IsValidation returns true if MG rejected the request data (400 Bad Request or 422 Unprocessable Entity).
+func (err *HTTPClientError) IsValidation() bool {
+	return err != nil &&
+		(err.StatusCode == http.StatusBadRequest || err.StatusCode == http.StatusUnprocessableEntity)
+}
+
+// IsAuth returns true if MG rejected the transport token (401 Unauthorized or 403 Forbidden).
+func (err *HTTPClientError) IsAuth() bool {
+	return err != nil && (err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden)
+}
+
+// IsTemporary returns true if the request may succeed later: it has failed because of the network error,
+// rate limiting or gateway errors.
+func (err *HTTPClientError) IsTemporary() bool {
+	if err == nil {
+		return false
+	}
+
+	if err.BaseError != nil {
+		return !errors.Is(err.BaseError, context.Canceled)
+	}
+
+	switch err.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// AsClientError returns *HTTPClientError from the error chain or nil if there is none.
+// All its classification methods can be called on the nil value.
+//
+// Example:
+//
+//	_, _, err := client.Messages(request)
+//	if clientErr := AsClientError(err); clientErr.IsTemporary() {
+//		// schedule the retry
+//	}
 func AsClientError(err error) *HTTPClientError {
 	for {
 		if err == nil {
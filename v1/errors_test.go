@@ -2,6 +2,7 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewCriticalHTTPError(t *testing.T) {
@@ -70,3 +72,49 @@ func TestAsClientError(t *testing.T) {
 	assert.Nil(t, AsClientError(errors.New("arbitrary")))
 	assert.NotNil(t, AsClientError(NewCriticalHTTPError(errors.New("arbitrary"))))
 }
+
+func TestHTTPClientError_Classification(t *testing.T) {
+	var nilErr *HTTPClientError
+	assert.False(t, nilErr.IsNotFound())
+	assert.False(t, nilErr.IsTemporary())
+
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusNotFound}).IsNotFound())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusTooManyRequests}).IsRateLimited())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusTooManyRequests}).IsTemporary())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusBadRequest}).IsValidation())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusUnprocessableEntity}).IsValidation())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusUnauthorized}).IsAuth())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusForbidden}).IsAuth())
+	assert.True(t, (&HTTPClientError{StatusCode: http.StatusServiceUnavailable}).IsTemporary())
+	assert.False(t, (&HTTPClientError{StatusCode: http.StatusInternalServerError}).IsTemporary())
+	assert.False(t, (&HTTPClientError{StatusCode: http.StatusBadRequest}).IsTemporary())
+	assert.True(t, AsClientError(NewCriticalHTTPError(errors.New("EOF"))).IsTemporary())
+	assert.False(t, AsClientError(NewCriticalHTTPError(context.Canceled)).IsTemporary())
+}
+
+func TestNewAPIError(t *testing.T) {
+	body := []byte(`{"errors":["Message not found","Chat not found"]}`)
+	err := newAPIError(
+		&APIRequest{Method: http.MethodPut, Route: "/messages"},
+		&APIResponse{StatusCode: http.StatusNotFound, Body: body},
+	)
+
+	var clientErr *HTTPClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, "Message not found", clientErr.Error())
+	assert.Equal(t, []string{"Message not found", "Chat not found"}, clientErr.Errors)
+	assert.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	assert.Equal(t, http.MethodPut, clientErr.Method)
+	assert.Equal(t, "/messages", clientErr.Route)
+	assert.Equal(t, body, clientErr.Body)
+	assert.True(t, clientErr.IsNotFound())
+	assert.NotNil(t, clientErr.Response)
+
+	err = newAPIError(
+		&APIRequest{Method: http.MethodGet, Route: "/templates"},
+		&APIResponse{StatusCode: http.StatusForbidden, Body: []byte("<html>Forbidden</html>")},
+	)
+	assert.Equal(t, marshalError, err.Error())
+	assert.True(t, AsClientError(err).IsAuth())
+	assert.Equal(t, "<html>Forbidden</html>", string(AsClientError(err).Body))
+}
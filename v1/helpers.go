@@ -23,6 +23,19 @@ func buildLimitedRawResponse(resp *http.Response) ([]byte, error) {
 	return body, nil
 }
 
+// readLimitedResponse reads up to LimitResponse bytes of the response body and closes it.
+// It returns true if the body was truncated.
+func readLimitedResponse(resp *http.Response) ([]byte, bool, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, LimitResponse+1))
+	if len(body) > LimitResponse {
+		return body[:LimitResponse], true, err
+	}
+
+	return body, false, err
+}
+
 // BoolPtr returns provided boolean as pointer. Can be used while editing the integration module activity.
 func BoolPtr(v bool) *bool {
 	return &v
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"io"
+)
+
+// Client is the Transport API client. It is implemented by MgClient.
+// Depend on this interface instead of *MgClient to substitute the client in the tests
+// (see the github.com/retailcrm/mg-transport-api-client-go/v1/mgfake package).
+type Client interface {
+	// TransportTemplates returns templates list.
+	TransportTemplates() ([]Template, int, error)
+	// TransportTemplatesContext is the same as TransportTemplates but uses the provided context.
+	TransportTemplatesContext(ctx context.Context) ([]Template, int, error)
+	// ActivateTemplate activates template with provided structure.
+	ActivateTemplate(channelID uint64, request ActivateTemplateRequest) (int, error)
+	// ActivateTemplateContext is the same as ActivateTemplate but uses the provided context.
+	ActivateTemplateContext(ctx context.Context, channelID uint64, request ActivateTemplateRequest) (int, error)
+	// UpdateTemplate updates existing template by its code.
+	UpdateTemplate(channelID uint64, code string, request UpdateTemplateRequest) (int, error)
+	// UpdateTemplateContext is the same as UpdateTemplate but uses the provided context.
+	UpdateTemplateContext(
+		ctx context.Context, channelID uint64, code string, request UpdateTemplateRequest) (int, error)
+	// DeactivateTemplate deactivates the template by its code.
+	DeactivateTemplate(channelID uint64, templateCode string) (int, error)
+	// DeactivateTemplateContext is the same as DeactivateTemplate but uses the provided context.
+	DeactivateTemplateContext(ctx context.Context, channelID uint64, templateCode string) (int, error)
+
+	// TransportChannels returns channels for current transport.
+	TransportChannels(request Channels) ([]ChannelListItem, int, error)
+	// TransportChannelsContext is the same as TransportChannels but uses the provided context.
+	TransportChannelsContext(ctx context.Context, request Channels) ([]ChannelListItem, int, error)
+	// ActivateTransportChannel activates the channel with provided settings.
+	ActivateTransportChannel(request Channel) (ActivateResponse, int, error)
+	// ActivateTransportChannelContext is the same as ActivateTransportChannel but uses the provided context.
+	ActivateTransportChannelContext(ctx context.Context, request Channel) (ActivateResponse, int, error)
+	// UpdateTransportChannel updates an existing channel with provided settings.
+	UpdateTransportChannel(request Channel) (UpdateResponse, int, error)
+	// UpdateTransportChannelContext is the same as UpdateTransportChannel but uses the provided context.
+	UpdateTransportChannelContext(ctx context.Context, request Channel) (UpdateResponse, int, error)
+	// DeactivateTransportChannel deactivates the channel by its ID.
+	DeactivateTransportChannel(id uint64) (DeleteResponse, int, error)
+	// DeactivateTransportChannelContext is the same as DeactivateTransportChannel but uses the provided context.
+	DeactivateTransportChannelContext(ctx context.Context, id uint64) (DeleteResponse, int, error)
+
+	// Messages sends new message.
+	Messages(request SendData) (MessagesResponse, int, error)
+	// MessagesContext is the same as Messages but uses the provided context.
+	MessagesContext(ctx context.Context, request SendData) (MessagesResponse, int, error)
+	// MessagesHistory sends history message.
+	MessagesHistory(request SendHistoryMessageRequest) (MessagesResponse, int, error)
+	// MessagesHistoryContext is the same as MessagesHistory but uses the provided context.
+	MessagesHistoryContext(ctx context.Context, request SendHistoryMessageRequest) (MessagesResponse, int, error)
+	// AddMessageReaction adds reactions to the message.
+	AddMessageReaction(request ReactionRequest) (int, error)
+	// AddMessageReactionContext is the same as AddMessageReaction but uses the provided context.
+	AddMessageReactionContext(ctx context.Context, request ReactionRequest) (int, error)
+	// DeleteMessagesReaction removes reactions to the message.
+	DeleteMessagesReaction(request ReactionRequest) (int, error)
+	// DeleteMessagesReactionContext is the same as DeleteMessagesReaction but uses the provided context.
+	DeleteMessagesReactionContext(ctx context.Context, request ReactionRequest) (int, error)
+	// UpdateMessages edits existing message.
+	UpdateMessages(request EditMessageRequest) (MessagesResponse, int, error)
+	// UpdateMessagesContext is the same as UpdateMessages but uses the provided context.
+	UpdateMessagesContext(ctx context.Context, request EditMessageRequest) (MessagesResponse, int, error)
+	// MarkMessageRead send message read event to MG.
+	MarkMessageRead(request MarkMessageReadRequest) (MarkMessageReadResponse, int, error)
+	// MarkMessageReadContext is the same as MarkMessageRead but uses the provided context.
+	MarkMessageReadContext(
+		ctx context.Context, request MarkMessageReadRequest) (MarkMessageReadResponse, int, error)
+	// AckMessage sets success status for message or appends an error to message.
+	AckMessage(request AckMessageRequest) (int, error)
+	// AckMessageContext is the same as AckMessage but uses the provided context.
+	AckMessageContext(ctx context.Context, request AckMessageRequest) (int, error)
+	// ReadUntil will mark all messages from specified timestamp as read.
+	ReadUntil(request MarkMessagesReadUntilRequest) (*MarkMessagesReadUntilResponse, int, error)
+	// ReadUntilContext is the same as ReadUntil but uses the provided context.
+	ReadUntilContext(
+		ctx context.Context, request MarkMessagesReadUntilRequest) (*MarkMessagesReadUntilResponse, int, error)
+	// DeleteMessage removes the message.
+	DeleteMessage(request DeleteData) (*MessagesResponse, int, error)
+	// DeleteMessageContext is the same as DeleteMessage but uses the provided context.
+	DeleteMessageContext(ctx context.Context, request DeleteData) (*MessagesResponse, int, error)
+	// RestoreMessage restores deleted message.
+	RestoreMessage(request RestoreMessageRequest) (MessagesResponse, int, error)
+	// RestoreMessageContext is the same as RestoreMessage but uses the provided context.
+	RestoreMessageContext(ctx context.Context, request RestoreMessageRequest) (MessagesResponse, int, error)
+
+	// GetFile returns file information by its ID.
+	GetFile(request string) (FullFileResponse, int, error)
+	// GetFileContext is the same as GetFile but uses the provided context.
+	GetFileContext(ctx context.Context, request string) (FullFileResponse, int, error)
+	// UploadFile uploads a file.
+	UploadFile(request io.Reader) (UploadFileResponse, int, error)
+	// UploadFileContext is the same as UploadFile but uses the provided context.
+	UploadFileContext(ctx context.Context, request io.Reader) (UploadFileResponse, int, error)
+	// UploadFileByURL uploads a file from provided URL.
+	UploadFileByURL(request UploadFileByUrlRequest) (UploadFileResponse, int, error)
+	// UploadFileByURLContext is the same as UploadFileByURL but uses the provided context.
+	UploadFileByURLContext(ctx context.Context, request UploadFileByUrlRequest) (UploadFileResponse, int, error)
+}
+
+var _ Client = (*MgClient)(nil)
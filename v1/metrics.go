@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+)
+
+// NoopMetrics discards all metrics. It is used by the client by default.
+var NoopMetrics Metrics = &noopMetrics{}
+
+// Metrics receives the client metrics. Implementations must be safe for concurrent use.
+// The github.com/retailcrm/mg-transport-api-client-go/v1/prommetrics package contains the implementation
+// which exposes the metrics in the Prometheus text format.
+type Metrics interface {
+	// RequestDone is called when the API call is finished, after all retries.
+	RequestDone(request RequestMetrics)
+	// LimiterWait is called with the time spent waiting for the rate limiter before every attempt.
+	LimiterWait(method, route string, wait time.Duration)
+}
+
+// RequestMetrics describes the finished API call.
+type RequestMetrics struct {
+	// Method of the request.
+	Method string
+	// Route template of the request, e.g. "/channels/{id}".
+	Route string
+	// StatusCode of the last response. It will be 0 if the last attempt has failed without a response.
+	StatusCode int
+	// Duration of the call including retries and rate limiter waits.
+	Duration time.Duration
+	// Attempts is the number of the sent attempts. Attempts-1 requests were retried.
+	Attempts int
+	// RateLimited is the number of the attempts which received 429 status.
+	RateLimited int
+	// ResponseSize is the size of the last response body in bytes.
+	ResponseSize int
+	// Err is the error returned by the call if any.
+	Err error
+}
+
+// WithMetrics sets the metrics receiver into the Client.
+func (c *MgClient) WithMetrics(metrics Metrics) *MgClient {
+	c.metrics = metrics
+	return c
+}
+
+func (c *MgClient) metricsOrDefault() Metrics {
+	if c.metrics != nil {
+		return c.metrics
+	}
+	return NoopMetrics
+}
+
+// recordRequest reports the finished API call to the metrics.
+func (c *MgClient) recordRequest(req *APIRequest, resp *APIResponse, err error, duration time.Duration) {
+	info := RequestMetrics{
+		Method:      req.Method,
+		Route:       req.Route,
+		Duration:    duration,
+		Attempts:    req.Attempt,
+		RateLimited: req.rateLimited,
+		Err:         err,
+	}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+		info.ResponseSize = len(resp.Body)
+	}
+
+	c.metricsOrDefault().RequestDone(info)
+}
+
+// countRateLimited counts 429 responses to the request.
+func countRateLimited(req *APIRequest, resp *APIResponse) {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		req.rateLimited++
+	}
+}
+
+type noopMetrics struct{}
+
+func (m *noopMetrics) RequestDone(RequestMetrics) {}
+
+func (m *noopMetrics) LimiterWait(string, string, time.Duration) {}
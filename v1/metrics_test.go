@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []RequestMetrics
+	waits    []string
+}
+
+func (m *recordingMetrics) RequestDone(request RequestMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, request)
+}
+
+func (m *recordingMetrics) LimiterWait(method, route string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.waits = append(m.waits, method+" "+route)
+}
+
+func TestMetrics_RequestDone(t *testing.T) {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	metrics := &recordingMetrics{}
+	c := New(srv.URL, "token").WithLimiter(NoopLimiter).WithMetrics(metrics)
+
+	_, _, _ = c.DeactivateTransportChannel(1)
+	_, _, err := c.Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+
+	require.Len(t, metrics.requests, 2)
+	assert.Equal(t, http.MethodDelete, metrics.requests[0].Method)
+	assert.Equal(t, "/channels/{id}", metrics.requests[0].Route)
+	assert.Equal(t, 2, metrics.requests[0].Attempts)
+	assert.Equal(t, 1, metrics.requests[0].RateLimited)
+	assert.Equal(t, http.StatusOK, metrics.requests[0].StatusCode)
+
+	assert.Equal(t, RequestMetrics{
+		Method:       http.MethodPost,
+		Route:        "/messages",
+		StatusCode:   http.StatusOK,
+		Duration:     metrics.requests[1].Duration,
+		Attempts:     1,
+		ResponseSize: len(`{"message_id":1,"id":"file_id"}`),
+	}, metrics.requests[1])
+	assert.Positive(t, metrics.requests[1].Duration)
+	assert.Equal(t, []string{
+		"DELETE /channels/{id}", "DELETE /channels/{id}", "POST /messages",
+	}, metrics.waits)
+}
+
+func TestMetrics_NetworkError(t *testing.T) {
+	metrics := &recordingMetrics{}
+	c := New("http://127.0.0.1:1", "token").WithMetrics(metrics)
+
+	_, status, err := c.TransportTemplates()
+	require.Error(t, err)
+	assert.Zero(t, status)
+	require.Len(t, metrics.requests, 1)
+	assert.Zero(t, metrics.requests[0].StatusCode)
+	assert.Equal(t, 1, metrics.requests[0].Attempts)
+	assert.Error(t, metrics.requests[0].Err)
+	assert.Empty(t, metrics.waits, "limiter waits are not reported without the limiter")
+}
@@ -0,0 +1,410 @@
+// Code generated by gen.go; DO NOT EDIT.
+
+package mgfake
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+var _ v1.Client = (*Fake)(nil)
+
+// Fake implements v1.Client. It records every call and returns the result of the corresponding
+// ...Func field. If the field is not set, the zero value with http.StatusOK is returned.
+// Methods without the context call their ...Context counterparts.
+type Fake struct {
+	recorder
+
+	// TransportTemplatesFunc is called by TransportTemplates and TransportTemplatesContext.
+	TransportTemplatesFunc func(ctx context.Context) ([]v1.Template, int, error)
+	// ActivateTemplateFunc is called by ActivateTemplate and ActivateTemplateContext.
+	ActivateTemplateFunc func(ctx context.Context, channelID uint64, request v1.ActivateTemplateRequest) (int, error)
+	// UpdateTemplateFunc is called by UpdateTemplate and UpdateTemplateContext.
+	UpdateTemplateFunc func(
+		ctx context.Context, channelID uint64, code string, request v1.UpdateTemplateRequest) (int, error)
+	// DeactivateTemplateFunc is called by DeactivateTemplate and DeactivateTemplateContext.
+	DeactivateTemplateFunc func(ctx context.Context, channelID uint64, templateCode string) (int, error)
+	// TransportChannelsFunc is called by TransportChannels and TransportChannelsContext.
+	TransportChannelsFunc func(ctx context.Context, request v1.Channels) ([]v1.ChannelListItem, int, error)
+	// ActivateTransportChannelFunc is called by ActivateTransportChannel and ActivateTransportChannelContext.
+	ActivateTransportChannelFunc func(ctx context.Context, request v1.Channel) (v1.ActivateResponse, int, error)
+	// UpdateTransportChannelFunc is called by UpdateTransportChannel and UpdateTransportChannelContext.
+	UpdateTransportChannelFunc func(ctx context.Context, request v1.Channel) (v1.UpdateResponse, int, error)
+	// DeactivateTransportChannelFunc is called by DeactivateTransportChannel and DeactivateTransportChannelContext.
+	DeactivateTransportChannelFunc func(ctx context.Context, id uint64) (v1.DeleteResponse, int, error)
+	// MessagesFunc is called by Messages and MessagesContext.
+	MessagesFunc func(ctx context.Context, request v1.SendData) (v1.MessagesResponse, int, error)
+	// MessagesHistoryFunc is called by MessagesHistory and MessagesHistoryContext.
+	MessagesHistoryFunc func(ctx context.Context, request v1.SendHistoryMessageRequest) (v1.MessagesResponse, int, error)
+	// AddMessageReactionFunc is called by AddMessageReaction and AddMessageReactionContext.
+	AddMessageReactionFunc func(ctx context.Context, request v1.ReactionRequest) (int, error)
+	// DeleteMessagesReactionFunc is called by DeleteMessagesReaction and DeleteMessagesReactionContext.
+	DeleteMessagesReactionFunc func(ctx context.Context, request v1.ReactionRequest) (int, error)
+	// UpdateMessagesFunc is called by UpdateMessages and UpdateMessagesContext.
+	UpdateMessagesFunc func(ctx context.Context, request v1.EditMessageRequest) (v1.MessagesResponse, int, error)
+	// MarkMessageReadFunc is called by MarkMessageRead and MarkMessageReadContext.
+	MarkMessageReadFunc func(
+		ctx context.Context, request v1.MarkMessageReadRequest) (v1.MarkMessageReadResponse, int, error)
+	// AckMessageFunc is called by AckMessage and AckMessageContext.
+	AckMessageFunc func(ctx context.Context, request v1.AckMessageRequest) (int, error)
+	// ReadUntilFunc is called by ReadUntil and ReadUntilContext.
+	ReadUntilFunc func(
+		ctx context.Context, request v1.MarkMessagesReadUntilRequest) (*v1.MarkMessagesReadUntilResponse, int, error)
+	// DeleteMessageFunc is called by DeleteMessage and DeleteMessageContext.
+	DeleteMessageFunc func(ctx context.Context, request v1.DeleteData) (*v1.MessagesResponse, int, error)
+	// RestoreMessageFunc is called by RestoreMessage and RestoreMessageContext.
+	RestoreMessageFunc func(ctx context.Context, request v1.RestoreMessageRequest) (v1.MessagesResponse, int, error)
+	// GetFileFunc is called by GetFile and GetFileContext.
+	GetFileFunc func(ctx context.Context, request string) (v1.FullFileResponse, int, error)
+	// UploadFileFunc is called by UploadFile and UploadFileContext.
+	UploadFileFunc func(ctx context.Context, request io.Reader) (v1.UploadFileResponse, int, error)
+	// UploadFileByURLFunc is called by UploadFileByURL and UploadFileByURLContext.
+	UploadFileByURLFunc func(ctx context.Context, request v1.UploadFileByUrlRequest) (v1.UploadFileResponse, int, error)
+}
+
+// TransportTemplates calls TransportTemplatesContext with the background context.
+func (f *Fake) TransportTemplates() ([]v1.Template, int, error) {
+	return f.TransportTemplatesContext(context.Background())
+}
+
+// TransportTemplatesContext records the call and returns the result of TransportTemplatesFunc.
+func (f *Fake) TransportTemplatesContext(ctx context.Context) ([]v1.Template, int, error) {
+	f.record("TransportTemplates")
+	if fn := f.TransportTemplatesFunc; fn != nil {
+		return fn(ctx)
+	}
+
+	var resp []v1.Template
+	return resp, http.StatusOK, nil
+}
+
+// ActivateTemplate calls ActivateTemplateContext with the background context.
+func (f *Fake) ActivateTemplate(channelID uint64, request v1.ActivateTemplateRequest) (int, error) {
+	return f.ActivateTemplateContext(context.Background(), channelID, request)
+}
+
+// ActivateTemplateContext records the call and returns the result of ActivateTemplateFunc.
+func (f *Fake) ActivateTemplateContext(ctx context.Context, channelID uint64, request v1.ActivateTemplateRequest) (int, error) {
+	f.record("ActivateTemplate", channelID, request)
+	if fn := f.ActivateTemplateFunc; fn != nil {
+		return fn(ctx, channelID, request)
+	}
+
+	return http.StatusOK, nil
+}
+
+// UpdateTemplate calls UpdateTemplateContext with the background context.
+func (f *Fake) UpdateTemplate(channelID uint64, code string, request v1.UpdateTemplateRequest) (int, error) {
+	return f.UpdateTemplateContext(context.Background(), channelID, code, request)
+}
+
+// UpdateTemplateContext records the call and returns the result of UpdateTemplateFunc.
+func (f *Fake) UpdateTemplateContext(
+	ctx context.Context, channelID uint64, code string, request v1.UpdateTemplateRequest) (int, error) {
+	f.record("UpdateTemplate", channelID, code, request)
+	if fn := f.UpdateTemplateFunc; fn != nil {
+		return fn(ctx, channelID, code, request)
+	}
+
+	return http.StatusOK, nil
+}
+
+// DeactivateTemplate calls DeactivateTemplateContext with the background context.
+func (f *Fake) DeactivateTemplate(channelID uint64, templateCode string) (int, error) {
+	return f.DeactivateTemplateContext(context.Background(), channelID, templateCode)
+}
+
+// DeactivateTemplateContext records the call and returns the result of DeactivateTemplateFunc.
+func (f *Fake) DeactivateTemplateContext(ctx context.Context, channelID uint64, templateCode string) (int, error) {
+	f.record("DeactivateTemplate", channelID, templateCode)
+	if fn := f.DeactivateTemplateFunc; fn != nil {
+		return fn(ctx, channelID, templateCode)
+	}
+
+	return http.StatusOK, nil
+}
+
+// TransportChannels calls TransportChannelsContext with the background context.
+func (f *Fake) TransportChannels(request v1.Channels) ([]v1.ChannelListItem, int, error) {
+	return f.TransportChannelsContext(context.Background(), request)
+}
+
+// TransportChannelsContext records the call and returns the result of TransportChannelsFunc.
+func (f *Fake) TransportChannelsContext(ctx context.Context, request v1.Channels) ([]v1.ChannelListItem, int, error) {
+	f.record("TransportChannels", request)
+	if fn := f.TransportChannelsFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp []v1.ChannelListItem
+	return resp, http.StatusOK, nil
+}
+
+// ActivateTransportChannel calls ActivateTransportChannelContext with the background context.
+func (f *Fake) ActivateTransportChannel(request v1.Channel) (v1.ActivateResponse, int, error) {
+	return f.ActivateTransportChannelContext(context.Background(), request)
+}
+
+// ActivateTransportChannelContext records the call and returns the result of ActivateTransportChannelFunc.
+func (f *Fake) ActivateTransportChannelContext(ctx context.Context, request v1.Channel) (v1.ActivateResponse, int, error) {
+	f.record("ActivateTransportChannel", request)
+	if fn := f.ActivateTransportChannelFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		ActivateResponse
+	return resp, http.StatusOK, nil
+}
+
+// UpdateTransportChannel calls UpdateTransportChannelContext with the background context.
+func (f *Fake) UpdateTransportChannel(request v1.Channel) (v1.UpdateResponse, int, error) {
+	return f.UpdateTransportChannelContext(context.Background(), request)
+}
+
+// UpdateTransportChannelContext records the call and returns the result of UpdateTransportChannelFunc.
+func (f *Fake) UpdateTransportChannelContext(ctx context.Context, request v1.Channel) (v1.UpdateResponse, int, error) {
+	f.record("UpdateTransportChannel", request)
+	if fn := f.UpdateTransportChannelFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		UpdateResponse
+	return resp, http.StatusOK, nil
+}
+
+// DeactivateTransportChannel calls DeactivateTransportChannelContext with the background context.
+func (f *Fake) DeactivateTransportChannel(id uint64) (v1.DeleteResponse, int, error) {
+	return f.DeactivateTransportChannelContext(context.Background(), id)
+}
+
+// DeactivateTransportChannelContext records the call and returns the result of DeactivateTransportChannelFunc.
+func (f *Fake) DeactivateTransportChannelContext(ctx context.Context, id uint64) (v1.DeleteResponse, int, error) {
+	f.record("DeactivateTransportChannel", id)
+	if fn := f.DeactivateTransportChannelFunc; fn != nil {
+		return fn(ctx, id)
+	}
+
+	var resp v1.
+		DeleteResponse
+	return resp, http.StatusOK, nil
+}
+
+// Messages calls MessagesContext with the background context.
+func (f *Fake) Messages(request v1.SendData) (v1.MessagesResponse, int, error) {
+	return f.MessagesContext(context.Background(), request)
+}
+
+// MessagesContext records the call and returns the result of MessagesFunc.
+func (f *Fake) MessagesContext(ctx context.Context, request v1.SendData) (v1.MessagesResponse, int, error) {
+	f.record("Messages", request)
+	if fn := f.MessagesFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		MessagesResponse
+	return resp, http.StatusOK, nil
+}
+
+// MessagesHistory calls MessagesHistoryContext with the background context.
+func (f *Fake) MessagesHistory(request v1.SendHistoryMessageRequest) (v1.MessagesResponse, int, error) {
+	return f.MessagesHistoryContext(context.Background(), request)
+}
+
+// MessagesHistoryContext records the call and returns the result of MessagesHistoryFunc.
+func (f *Fake) MessagesHistoryContext(ctx context.Context, request v1.SendHistoryMessageRequest) (v1.MessagesResponse, int, error) {
+	f.record("MessagesHistory", request)
+	if fn := f.MessagesHistoryFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		MessagesResponse
+	return resp, http.StatusOK, nil
+}
+
+// AddMessageReaction calls AddMessageReactionContext with the background context.
+func (f *Fake) AddMessageReaction(request v1.ReactionRequest) (int, error) {
+	return f.AddMessageReactionContext(context.Background(), request)
+}
+
+// AddMessageReactionContext records the call and returns the result of AddMessageReactionFunc.
+func (f *Fake) AddMessageReactionContext(ctx context.Context, request v1.ReactionRequest) (int, error) {
+	f.record("AddMessageReaction", request)
+	if fn := f.AddMessageReactionFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	return http.StatusOK, nil
+}
+
+// DeleteMessagesReaction calls DeleteMessagesReactionContext with the background context.
+func (f *Fake) DeleteMessagesReaction(request v1.ReactionRequest) (int, error) {
+	return f.DeleteMessagesReactionContext(context.Background(), request)
+}
+
+// DeleteMessagesReactionContext records the call and returns the result of DeleteMessagesReactionFunc.
+func (f *Fake) DeleteMessagesReactionContext(ctx context.Context, request v1.ReactionRequest) (int, error) {
+	f.record("DeleteMessagesReaction", request)
+	if fn := f.DeleteMessagesReactionFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	return http.StatusOK, nil
+}
+
+// UpdateMessages calls UpdateMessagesContext with the background context.
+func (f *Fake) UpdateMessages(request v1.EditMessageRequest) (v1.MessagesResponse, int, error) {
+	return f.UpdateMessagesContext(context.Background(), request)
+}
+
+// UpdateMessagesContext records the call and returns the result of UpdateMessagesFunc.
+func (f *Fake) UpdateMessagesContext(ctx context.Context, request v1.EditMessageRequest) (v1.MessagesResponse, int, error) {
+	f.record("UpdateMessages", request)
+	if fn := f.UpdateMessagesFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		MessagesResponse
+	return resp, http.StatusOK, nil
+}
+
+// MarkMessageRead calls MarkMessageReadContext with the background context.
+func (f *Fake) MarkMessageRead(request v1.MarkMessageReadRequest) (v1.MarkMessageReadResponse, int, error) {
+	return f.MarkMessageReadContext(context.Background(), request)
+}
+
+// MarkMessageReadContext records the call and returns the result of MarkMessageReadFunc.
+func (f *Fake) MarkMessageReadContext(
+	ctx context.Context, request v1.MarkMessageReadRequest) (v1.MarkMessageReadResponse, int, error) {
+	f.record("MarkMessageRead", request)
+	if fn := f.MarkMessageReadFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		MarkMessageReadResponse
+	return resp, http.StatusOK, nil
+}
+
+// AckMessage calls AckMessageContext with the background context.
+func (f *Fake) AckMessage(request v1.AckMessageRequest) (int, error) {
+	return f.AckMessageContext(context.Background(), request)
+}
+
+// AckMessageContext records the call and returns the result of AckMessageFunc.
+func (f *Fake) AckMessageContext(ctx context.Context, request v1.AckMessageRequest) (int, error) {
+	f.record("AckMessage", request)
+	if fn := f.AckMessageFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ReadUntil calls ReadUntilContext with the background context.
+func (f *Fake) ReadUntil(request v1.MarkMessagesReadUntilRequest) (*v1.MarkMessagesReadUntilResponse, int, error) {
+	return f.ReadUntilContext(context.Background(), request)
+}
+
+// ReadUntilContext records the call and returns the result of ReadUntilFunc.
+func (f *Fake) ReadUntilContext(
+	ctx context.Context, request v1.MarkMessagesReadUntilRequest) (*v1.MarkMessagesReadUntilResponse, int, error) {
+	f.record("ReadUntil", request)
+	if fn := f.ReadUntilFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp *v1.MarkMessagesReadUntilResponse
+	return resp, http.StatusOK, nil
+}
+
+// DeleteMessage calls DeleteMessageContext with the background context.
+func (f *Fake) DeleteMessage(request v1.DeleteData) (*v1.MessagesResponse, int, error) {
+	return f.DeleteMessageContext(context.Background(), request)
+}
+
+// DeleteMessageContext records the call and returns the result of DeleteMessageFunc.
+func (f *Fake) DeleteMessageContext(ctx context.Context, request v1.DeleteData) (*v1.MessagesResponse, int, error) {
+	f.record("DeleteMessage", request)
+	if fn := f.DeleteMessageFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp *v1.MessagesResponse
+	return resp, http.StatusOK, nil
+}
+
+// RestoreMessage calls RestoreMessageContext with the background context.
+func (f *Fake) RestoreMessage(request v1.RestoreMessageRequest) (v1.MessagesResponse, int, error) {
+	return f.RestoreMessageContext(context.Background(), request)
+}
+
+// RestoreMessageContext records the call and returns the result of RestoreMessageFunc.
+func (f *Fake) RestoreMessageContext(ctx context.Context, request v1.RestoreMessageRequest) (v1.MessagesResponse, int, error) {
+	f.record("RestoreMessage", request)
+	if fn := f.RestoreMessageFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		MessagesResponse
+	return resp, http.StatusOK, nil
+}
+
+// GetFile calls GetFileContext with the background context.
+func (f *Fake) GetFile(request string) (v1.FullFileResponse, int, error) {
+	return f.GetFileContext(context.Background(), request)
+}
+
+// GetFileContext records the call and returns the result of GetFileFunc.
+func (f *Fake) GetFileContext(ctx context.Context, request string) (v1.FullFileResponse, int, error) {
+	f.record("GetFile", request)
+	if fn := f.GetFileFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		FullFileResponse
+	return resp, http.StatusOK, nil
+}
+
+// UploadFile calls UploadFileContext with the background context.
+func (f *Fake) UploadFile(request io.Reader) (v1.UploadFileResponse, int, error) {
+	return f.UploadFileContext(context.Background(), request)
+}
+
+// UploadFileContext records the call and returns the result of UploadFileFunc.
+func (f *Fake) UploadFileContext(ctx context.Context, request io.Reader) (v1.UploadFileResponse, int, error) {
+	f.record("UploadFile", request)
+	if fn := f.UploadFileFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		UploadFileResponse
+	return resp, http.StatusOK, nil
+}
+
+// UploadFileByURL calls UploadFileByURLContext with the background context.
+func (f *Fake) UploadFileByURL(request v1.UploadFileByUrlRequest) (v1.UploadFileResponse, int, error) {
+	return f.UploadFileByURLContext(context.Background(), request)
+}
+
+// UploadFileByURLContext records the call and returns the result of UploadFileByURLFunc.
+func (f *Fake) UploadFileByURLContext(ctx context.Context, request v1.UploadFileByUrlRequest) (v1.UploadFileResponse, int, error) {
+	f.record("UploadFileByURL", request)
+	if fn := f.UploadFileByURLFunc; fn != nil {
+		return fn(ctx, request)
+	}
+
+	var resp v1.
+		UploadFileResponse
+	return resp, http.StatusOK, nil
+}
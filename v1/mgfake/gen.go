@@ -0,0 +1,176 @@
+//go:build ignore
+
+// This program generates fake_gen.go from the v1.Client interface. Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	source = "../interface.go"
+	target = "fake_gen.go"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, nil, 0)
+	if err != nil {
+		log.Fatalf("cannot parse %s: %s", source, err)
+	}
+
+	methods := clientMethods(file)
+	if len(methods) == 0 {
+		log.Fatalf("Client interface was not found in %s", source)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	writeStruct(&buf, fset, methods)
+	for _, method := range methods {
+		writeMethod(&buf, fset, method)
+	}
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("cannot format generated code: %s\n%s", err, buf.String())
+	}
+
+	if err := os.WriteFile(target, code, 0600); err != nil {
+		log.Fatalf("cannot write %s: %s", target, err)
+	}
+}
+
+const header = `// Code generated by gen.go; DO NOT EDIT.
+
+package mgfake
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+var _ v1.Client = (*Fake)(nil)
+
+`
+
+func clientMethods(file *ast.File) []*ast.Field {
+	var methods []*ast.Field
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != "Client" {
+			return true
+		}
+		for _, method := range spec.Type.(*ast.InterfaceType).Methods.List {
+			qualifyTypes(method.Type)
+			methods = append(methods, method)
+		}
+		return false
+	})
+	return methods
+}
+
+// qualifyTypes prefixes the v1 package types with the package name.
+func qualifyTypes(node ast.Node) {
+	var qualify func(expr ast.Expr) ast.Expr
+	qualify = func(expr ast.Expr) ast.Expr {
+		switch typed := expr.(type) {
+		case *ast.Ident:
+			if ast.IsExported(typed.Name) {
+				return &ast.SelectorExpr{X: ast.NewIdent("v1"), Sel: typed}
+			}
+		case *ast.StarExpr:
+			typed.X = qualify(typed.X)
+		case *ast.ArrayType:
+			typed.Elt = qualify(typed.Elt)
+		}
+		return expr
+	}
+
+	fn := node.(*ast.FuncType)
+	for _, list := range []*ast.FieldList{fn.Params, fn.Results} {
+		for _, field := range list.List {
+			field.Type = qualify(field.Type)
+		}
+	}
+}
+
+func writeStruct(buf *bytes.Buffer, fset *token.FileSet, methods []*ast.Field) {
+	buf.WriteString("// Fake implements v1.Client. It records every call and returns the result of the corresponding\n")
+	buf.WriteString("// ...Func field. If the field is not set, the zero value with http.StatusOK is returned.\n")
+	buf.WriteString("// Methods without the context call their ...Context counterparts.\n")
+	buf.WriteString("type Fake struct {\n\trecorder\n\n")
+	for _, method := range methods {
+		name := method.Names[0].Name
+		if !strings.HasSuffix(name, "Context") {
+			continue
+		}
+		base := strings.TrimSuffix(name, "Context")
+		fmt.Fprintf(buf, "\t// %sFunc is called by %s and %s.\n", base, base, name)
+		fmt.Fprintf(buf, "\t%sFunc %s\n", base, typeString(fset, method.Type))
+	}
+	buf.WriteString("}\n\n")
+}
+
+func writeMethod(buf *bytes.Buffer, fset *token.FileSet, method *ast.Field) {
+	name := method.Names[0].Name
+	fn := method.Type.(*ast.FuncType)
+	signature := strings.TrimPrefix(typeString(fset, fn), "func")
+	args := paramNames(fn)
+
+	if !strings.HasSuffix(name, "Context") {
+		fmt.Fprintf(buf, "// %s calls %sContext with the background context.\n", name, name)
+		fmt.Fprintf(buf, "func (f *Fake) %s%s {\n", name, signature)
+		fmt.Fprintf(buf, "\treturn f.%sContext(%s)\n}\n\n", name, strings.Join(append([]string{"context.Background()"}, args...), ", "))
+		return
+	}
+
+	base := strings.TrimSuffix(name, "Context")
+	fmt.Fprintf(buf, "// %s records the call and returns the result of %sFunc.\n", name, base)
+	fmt.Fprintf(buf, "func (f *Fake) %s%s {\n", name, signature)
+	fmt.Fprintf(buf, "\tf.record(%q%s)\n", base, prefixed(args[1:]))
+	fmt.Fprintf(buf, "\tif fn := f.%sFunc; fn != nil {\n\t\treturn fn(%s)\n\t}\n\n", base, strings.Join(args, ", "))
+
+	results := fn.Results.List
+	if len(results) == 2 {
+		buf.WriteString("\treturn http.StatusOK, nil\n}\n\n")
+		return
+	}
+
+	fmt.Fprintf(buf, "\tvar resp %s\n\treturn resp, http.StatusOK, nil\n}\n\n", typeString(fset, results[0].Type))
+}
+
+func paramNames(fn *ast.FuncType) []string {
+	var names []string
+	for _, field := range fn.Params.List {
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+func prefixed(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(args, ", ")
+}
+
+func typeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
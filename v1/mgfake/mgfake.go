@@ -0,0 +1,85 @@
+// Package mgfake provides a fake implementation of the v1.Client interface for unit tests.
+//
+// Fake records every call and returns scripted responses:
+//
+//	fake := &mgfake.Fake{}
+//	fake.MessagesFunc = func(ctx context.Context, request v1.SendData) (v1.MessagesResponse, int, error) {
+//		return v1.MessagesResponse{MessageID: 1}, http.StatusOK, nil
+//	}
+//	fake.AckMessageFunc = func(ctx context.Context, request v1.AckMessageRequest) (int, error) {
+//		return http.StatusNotFound, mgfake.APIError(http.StatusNotFound, "Message not found")
+//	}
+//
+//	handler := NewHandler(fake) // the code under test accepts v1.Client
+//	handler.Process(update)
+//
+//	calls := fake.CallsTo("Messages")
+//	request := calls[0].Args[0].(v1.SendData)
+//
+// Calls of the methods with and without context are recorded under the same name (e.g. "Messages").
+package mgfake
+
+//go:generate go run gen.go
+
+import (
+	"sync"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// Call is the recorded method call.
+type Call struct {
+	// Method name without the Context suffix.
+	Method string
+	// Args of the call without the context.
+	Args []interface{}
+}
+
+type recorder struct {
+	calls []Call
+	mu    sync.Mutex
+}
+
+func (r *recorder) record(method string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns all recorded calls in order.
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns recorded calls of the provided method.
+func (r *recorder) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var calls []Call
+	for _, call := range r.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset removes all recorded calls.
+func (r *recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// APIError returns the error which looks like the MG error response with the provided status.
+func APIError(status int, errors ...string) error {
+	err := &v1.HTTPClientError{StatusCode: status, Errors: errors}
+	if len(errors) > 0 {
+		err.ErrorMsg = errors[0]
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package mgfake
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+func TestFake_DefaultResponse(t *testing.T) {
+	var client v1.Client = &Fake{}
+
+	resp, status, err := client.Messages(v1.SendData{ExternalChatID: "chat"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, v1.MessagesResponse{}, resp)
+
+	status, err = client.AckMessage(v1.AckMessageRequest{Channel: 1})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestFake_ScriptedResponse(t *testing.T) {
+	type ctxKey struct{}
+	fake := &Fake{}
+	fake.MessagesFunc = func(ctx context.Context, request v1.SendData) (v1.MessagesResponse, int, error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return v1.MessagesResponse{MessageID: 10}, http.StatusOK, nil
+	}
+	fake.AckMessageFunc = func(context.Context, v1.AckMessageRequest) (int, error) {
+		return http.StatusNotFound, APIError(http.StatusNotFound, "Message not found")
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	resp, _, err := fake.MessagesContext(ctx, v1.SendData{})
+	require.NoError(t, err)
+	assert.Equal(t, 10, resp.MessageID)
+
+	status, err := fake.AckMessage(v1.AckMessageRequest{})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.EqualError(t, err, "Message not found")
+	assert.True(t, v1.AsClientError(err).IsNotFound())
+}
+
+func TestFake_Calls(t *testing.T) {
+	fake := &Fake{}
+	_, _, _ = fake.Messages(v1.SendData{ExternalChatID: "1"})
+	_, _ = fake.DeactivateTemplate(1, "code")
+	_, _, _ = fake.MessagesContext(context.Background(), v1.SendData{ExternalChatID: "2"})
+
+	calls := fake.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "DeactivateTemplate", calls[1].Method)
+	assert.Equal(t, []interface{}{uint64(1), "code"}, calls[1].Args)
+
+	messages := fake.CallsTo("Messages")
+	require.Len(t, messages, 2)
+	assert.Equal(t, "2", messages[1].Args[0].(v1.SendData).ExternalChatID)
+
+	fake.Reset()
+	assert.Empty(t, fake.Calls())
+}
@@ -0,0 +1,200 @@
+package mgtest
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+type channel struct {
+	v1.Channel
+	createdAt     time.Time
+	updatedAt     *time.Time
+	activatedAt   time.Time
+	deactivatedAt *time.Time
+}
+
+func (c *channel) active() bool {
+	return c.deactivatedAt == nil
+}
+
+func (c *channel) listItem() v1.ChannelListItem {
+	item := v1.ChannelListItem{
+		ID:          c.ID,
+		ExternalID:  c.ExternalID,
+		Type:        c.Type,
+		Settings:    c.Settings,
+		CreatedAt:   c.createdAt.Format(time.RFC3339),
+		ActivatedAt: c.activatedAt.Format(time.RFC3339),
+		IsActive:    c.active(),
+	}
+	if c.Name != "" {
+		name := c.Name
+		item.Name = &name
+	}
+	if c.updatedAt != nil {
+		updatedAt := c.updatedAt.Format(time.RFC3339)
+		item.UpdatedAt = &updatedAt
+	}
+	if c.deactivatedAt != nil {
+		deactivatedAt := c.deactivatedAt.Format(time.RFC3339)
+		item.DeactivatedAt = &deactivatedAt
+	}
+
+	return item
+}
+
+// Channels returns all channels known to the server including the deactivated ones.
+func (s *Server) Channels() []v1.ChannelListItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]v1.ChannelListItem, 0, len(s.channels))
+	for _, ch := range s.channels {
+		items = append(items, ch.listItem())
+	}
+	slices.SortFunc(items, func(a, b v1.ChannelListItem) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return items
+}
+
+// activeChannel returns the active channel or the error message. The caller must hold the lock.
+func (s *Server) activeChannel(id uint64) (*channel, string) {
+	ch, ok := s.channels[id]
+	if !ok {
+		return nil, "Channel not found"
+	}
+	if !ch.active() {
+		return nil, "Channel is deactivated"
+	}
+
+	return ch, ""
+}
+
+func (s *Server) listChannels(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	id, _ := strconv.ParseUint(query.Get("id"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	types := query["types"]
+	onlyActive := query.Get("active") == "true"
+
+	items := []v1.ChannelListItem{}
+	for _, item := range s.Channels() {
+		if (id != 0 && item.ID != id) || (onlyActive && !item.IsActive) ||
+			(len(types) > 0 && !slices.Contains(types, item.Type)) {
+			continue
+		}
+		if limit > 0 && len(items) == limit {
+			break
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) activateChannel(w http.ResponseWriter, r *http.Request) {
+	var req v1.Channel
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Type == "" {
+		writeErrors(w, http.StatusBadRequest, "Channel type is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ch, ok := s.channels[req.ID]
+	switch {
+	case req.ID == 0:
+		req.ID = uint64(s.nextID())
+		ch = &channel{createdAt: now}
+		s.channels[req.ID] = ch
+	case !ok:
+		writeErrors(w, http.StatusNotFound, "Channel not found")
+		return
+	case ch.active():
+		writeErrors(w, http.StatusBadRequest, "Channel is already activated")
+		return
+	default:
+		ch.updatedAt = &now
+	}
+
+	ch.Channel = req
+	ch.activatedAt = now
+	ch.deactivatedAt = nil
+
+	writeJSON(w, http.StatusCreated, v1.ActivateResponse{
+		ChannelID:   ch.ID,
+		ExternalID:  ch.ExternalID,
+		ActivatedAt: now,
+	})
+}
+
+func (s *Server) updateChannel(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req v1.Channel
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, msg := s.activeChannel(id)
+	if ch == nil {
+		writeErrors(w, http.StatusNotFound, msg)
+		return
+	}
+
+	now := s.now()
+	req.ID = id
+	if req.Type == "" {
+		req.Type = ch.Type
+	}
+	ch.Channel = req
+	ch.updatedAt = &now
+
+	writeJSON(w, http.StatusOK, v1.UpdateResponse{
+		ChannelID:  ch.ID,
+		ExternalID: ch.ExternalID,
+		UpdatedAt:  now,
+	})
+}
+
+func (s *Server) deactivateChannel(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, msg := s.activeChannel(id)
+	if ch == nil {
+		writeErrors(w, http.StatusNotFound, msg)
+		return
+	}
+
+	now := s.now()
+	ch.deactivatedAt = &now
+
+	writeJSON(w, http.StatusOK, v1.DeleteResponse{
+		ChannelID:     ch.ID,
+		DeactivatedAt: now,
+	})
+}
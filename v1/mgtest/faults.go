@@ -0,0 +1,88 @@
+package mgtest
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault is an error response which is returned instead of handling the request.
+type Fault struct {
+	// Method of the affected requests. Empty value matches any method.
+	Method string
+	// Path of the affected requests relative to the API prefix, e.g. "/messages" or "/channels/1".
+	// Empty value matches any path.
+	Path string
+	// Status of the response.
+	Status int
+	// Errors in the response body.
+	Errors []string
+	// RetryAfter is sent in the Retry-After header if it's positive. It's rounded up to whole seconds,
+	// so sub-second values are sent as 1.
+	RetryAfter time.Duration
+	// Times is the number of the requests affected by the fault. Zero means that all requests are affected.
+	Times int
+}
+
+// InjectFault adds the fault. Faults are matched in the order they were added.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// FailNext makes the next times requests to the path fail with the provided status.
+func (s *Server) FailNext(method, path string, status, times int) {
+	s.InjectFault(Fault{Method: method, Path: path, Status: status, Times: times})
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+func (s *Server) serveFault(w http.ResponseWriter, r *http.Request) bool {
+	fault := s.matchFault(r.Method, strings.TrimPrefix(r.URL.Path, apiPrefix))
+	if fault == nil {
+		return false
+	}
+
+	if fault.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(fault.RetryAfter.Seconds()))))
+	}
+
+	errors := fault.Errors
+	if len(errors) == 0 {
+		errors = []string{http.StatusText(fault.Status)}
+	}
+
+	writeErrors(w, fault.Status, errors...)
+	return true
+}
+
+func (s *Server) matchFault(method, path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, fault := range s.faults {
+		if (fault.Method != "" && fault.Method != method) || (fault.Path != "" && fault.Path != path) {
+			continue
+		}
+
+		matched := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+
+		return &matched
+	}
+
+	return nil
+}
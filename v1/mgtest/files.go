@@ -0,0 +1,147 @@
+package mgtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+type file struct {
+	v1.UploadFileResponse
+	content []byte
+}
+
+// FileContent returns the contents of the uploaded file. Files uploaded by URL have no contents.
+func (s *Server) FileContent(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[id]
+	if !ok {
+		return nil, false
+	}
+
+	return f.content, true
+}
+
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(io.LimitReader(r.Body, v1.FileSizeLimit+1))
+	switch {
+	case err != nil:
+		writeErrors(w, http.StatusBadRequest, "Cannot read the file: "+err.Error())
+		return
+	case len(content) == 0:
+		writeErrors(w, http.StatusBadRequest, "File is empty")
+		return
+	case len(content) > v1.FileSizeLimit:
+		writeErrors(w, http.StatusBadRequest, "File size exceeds the limit")
+		return
+	}
+
+	hash := sha256.Sum256(content)
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	s.storeFile(w, &file{
+		UploadFileResponse: v1.UploadFileResponse{
+			Hash:     hex.EncodeToString(hash[:]),
+			MimeType: mimeType,
+			Size:     len(content),
+		},
+		content: content,
+	})
+}
+
+func (s *Server) uploadFileByURL(w http.ResponseWriter, r *http.Request) {
+	var req v1.UploadFileByUrlRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeErrors(w, http.StatusBadRequest, "Invalid file URL")
+		return
+	}
+
+	mimeType := mime.TypeByExtension(path.Ext(u.Path))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	mimeType, _, _ = mime.ParseMediaType(mimeType)
+
+	s.storeFile(w, &file{
+		UploadFileResponse: v1.UploadFileResponse{
+			MimeType: mimeType,
+			Url:      &req.Url,
+		},
+	})
+}
+
+func (s *Server) storeFile(w http.ResponseWriter, f *file) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", s.nextID())
+	f.Type = fileType(f.MimeType)
+	f.CreatedAt = s.now().UTC().Truncate(time.Second)
+	s.files[f.ID] = f
+
+	writeJSON(w, http.StatusOK, f.UploadFileResponse)
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[r.PathValue("id")]
+	if !ok {
+		writeErrors(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	fileURL := s.URL + "/download/" + f.ID
+	if f.Url != nil {
+		fileURL = *f.Url
+	}
+
+	writeJSON(w, http.StatusOK, v1.FullFileResponse{
+		ID:       f.ID,
+		Type:     f.Type,
+		Size:     f.Size,
+		Url:      fileURL,
+		MimeType: f.MimeType,
+	})
+}
+
+func (s *Server) downloadFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	f, ok := s.files[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok || f.content == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", f.MimeType)
+	_, _ = w.Write(f.content)
+}
+
+func fileType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return v1.MsgTypeImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return v1.MsgTypeAudio
+	default:
+		return v1.MsgTypeFile
+	}
+}
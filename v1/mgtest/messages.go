@@ -0,0 +1,452 @@
+package mgtest
+
+import (
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// Message is a message stored by the server.
+type Message struct {
+	ID             int64
+	ChannelID      uint64
+	ExternalID     string
+	ExternalChatID string
+	Customer       v1.Customer
+	Type           string
+	Text           string
+	Items          []v1.Item
+	// History is true for the messages imported via MessagesHistory.
+	History bool
+	// Outgoing is true for the messages sent to the transport via SendMessage.
+	Outgoing  bool
+	Read      bool
+	Deleted   bool
+	Edited    bool
+	Reactions []string
+	CreatedAt time.Time
+}
+
+// Messages returns copies of all messages known to the server.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Message, 0, len(s.messages))
+	for _, msg := range s.messages {
+		cp := *msg
+		cp.Items = slices.Clone(msg.Items)
+		cp.Reactions = slices.Clone(msg.Reactions)
+		messages = append(messages, cp)
+	}
+
+	return messages
+}
+
+// Acks returns all AckMessage requests received by the server.
+func (s *Server) Acks() []v1.AckMessageRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.acks)
+}
+
+// message returns the message by its ID or external ID. The caller must hold the lock.
+func (s *Server) message(channelID uint64, id *int64, externalID string) *Message {
+	if id == nil && externalID == "" {
+		return nil
+	}
+
+	for _, msg := range s.messages {
+		if msg.ChannelID != channelID {
+			continue
+		}
+		if (id != nil && msg.ID == *id) || (id == nil && msg.ExternalID == externalID) {
+			return msg
+		}
+	}
+
+	return nil
+}
+
+// lookupMessage returns the message or writes an error response. The caller must hold the lock.
+func (s *Server) lookupMessage(w http.ResponseWriter, channelID uint64, id *int64, externalID string) *Message {
+	if _, msg := s.activeChannel(channelID); msg != "" {
+		writeErrors(w, http.StatusBadRequest, msg)
+		return nil
+	}
+
+	msg := s.message(channelID, id, externalID)
+	if msg == nil {
+		writeErrors(w, http.StatusNotFound, "Message not found")
+	}
+
+	return msg
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.SendData
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	msg := &Message{
+		ChannelID:      req.Channel,
+		ExternalID:     req.Message.ExternalID,
+		ExternalChatID: req.ExternalChatID,
+		Customer:       req.Customer,
+		Type:           req.Message.Type,
+		Text:           req.Message.Text,
+		Items:          req.Message.Items,
+	}
+	if req.Message.CreatedAt != nil {
+		msg.CreatedAt = *req.Message.CreatedAt
+	}
+
+	s.createMessage(w, msg, true)
+}
+
+func (s *Server) sendHistoryMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.SendHistoryMessageRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	msg := &Message{
+		ChannelID:      req.ChannelID,
+		ExternalID:     req.Message.ExternalID,
+		ExternalChatID: req.ExternalChatID,
+		Type:           req.Message.Type,
+		Text:           req.Message.Text,
+		Items:          req.Message.Items,
+		History:        true,
+	}
+	if req.Customer != nil {
+		msg.Customer = *req.Customer
+	}
+	if req.Message.CreatedAt != nil {
+		msg.CreatedAt = *req.Message.CreatedAt
+	}
+
+	s.createMessage(w, msg, req.Customer != nil)
+}
+
+func (s *Server) createMessage(w http.ResponseWriter, msg *Message, customerRequired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []string
+	ch, chErr := s.activeChannel(msg.ChannelID)
+	if ch == nil {
+		errs = append(errs, chErr)
+	}
+	if msg.ExternalChatID == "" {
+		errs = append(errs, "External chat ID is required")
+	}
+	if customerRequired && msg.Customer.ExternalID == "" {
+		errs = append(errs, "Customer external ID is required")
+	}
+	if msg.ExternalID != "" && s.message(msg.ChannelID, nil, msg.ExternalID) != nil {
+		errs = append(errs, "Message with such external ID already exists")
+	}
+	if ch != nil {
+		errs = append(errs, validateContent(ch, msg)...)
+	}
+	if len(errs) > 0 {
+		writeErrors(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	now := s.now()
+	msg.ID = s.nextID()
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = now
+	}
+	s.messages = append(s.messages, msg)
+
+	writeJSON(w, http.StatusOK, v1.MessagesResponse{MessageID: int(msg.ID), Time: now})
+}
+
+func validateContent(ch *channel, msg *Message) []string {
+	var creating string
+	settings := ch.Settings
+
+	switch msg.Type {
+	case v1.MsgTypeText:
+		creating = settings.Text.Creating
+	case v1.MsgTypeFile:
+		creating = settings.File.Creating
+	case v1.MsgTypeImage:
+		creating = settings.Image.Creating
+	case v1.MsgTypeAudio:
+		creating = settings.Audio.Creating
+	case v1.MsgTypeProduct:
+		creating = settings.Product.Creating
+	case v1.MsgTypeOrder:
+		creating = settings.Order.Creating
+	case "":
+		return []string{"Message type is required"}
+	default:
+		return []string{"Unsupported message type: " + msg.Type}
+	}
+
+	var errs []string
+	if creating == v1.ChannelFeatureNone {
+		errs = append(errs, "Channel does not support messages of type "+msg.Type)
+	}
+
+	switch msg.Type {
+	case v1.MsgTypeText:
+		errs = append(errs, validateText(ch, msg.Text)...)
+	case v1.MsgTypeFile, v1.MsgTypeImage, v1.MsgTypeAudio:
+		if len(msg.Items) == 0 {
+			errs = append(errs, "Message items are required")
+		}
+	}
+
+	return errs
+}
+
+func validateText(ch *channel, text string) []string {
+	if text == "" {
+		return []string{"Message text is required"}
+	}
+
+	limit := int(ch.Settings.Text.MaxCharsCount)
+	if limit > 0 && utf8.RuneCountInString(text) > limit {
+		return []string{"Message text is too long"}
+	}
+
+	return nil
+}
+
+func (s *Server) editMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.EditMessageRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, req.Channel, req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+
+	var errs []string
+	if msg.Deleted {
+		errs = append(errs, "Message is deleted")
+	}
+	if msg.Type != v1.MsgTypeText {
+		errs = append(errs, "Only text messages can be edited")
+	}
+	if s.channels[req.Channel].Settings.Text.Editing == v1.ChannelFeatureNone {
+		errs = append(errs, "Channel does not support message editing")
+	}
+	errs = append(errs, validateText(s.channels[req.Channel], req.Message.Text)...)
+	if len(errs) > 0 {
+		writeErrors(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	msg.Text = req.Message.Text
+	msg.Edited = true
+
+	writeJSON(w, http.StatusOK, v1.MessagesResponse{MessageID: int(msg.ID), Time: s.now()})
+}
+
+func (s *Server) deleteMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.DeleteData
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, req.Channel, req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+	if msg.Deleted {
+		writeErrors(w, http.StatusBadRequest, "Message is already deleted")
+		return
+	}
+
+	msg.Deleted = true
+	writeJSON(w, http.StatusOK, v1.MessagesResponse{MessageID: int(msg.ID), Time: s.now()})
+}
+
+func (s *Server) restoreMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.RestoreMessageRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, uint64(req.ChannelID), req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+	if !msg.Deleted {
+		writeErrors(w, http.StatusBadRequest, "Message is not deleted")
+		return
+	}
+
+	msg.Deleted = false
+	writeJSON(w, http.StatusOK, v1.MessagesResponse{MessageID: int(msg.ID), Time: s.now()})
+}
+
+func (s *Server) readMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.MarkMessageReadRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, req.ChannelID, req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+
+	msg.Read = true
+	writeJSON(w, http.StatusOK, v1.MarkMessageReadResponse{})
+}
+
+func (s *Server) readUntil(w http.ResponseWriter, r *http.Request) {
+	var req v1.MarkMessagesReadUntilRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []string
+	if _, msg := s.activeChannel(req.ChannelID); msg != "" {
+		errs = append(errs, msg)
+	}
+	if req.CustomerExternalID == "" {
+		errs = append(errs, "Customer external ID is required")
+	}
+	if req.Until.IsZero() {
+		errs = append(errs, "Until is required")
+	}
+	if len(errs) > 0 {
+		writeErrors(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	resp := v1.MarkMessagesReadUntilResponse{IDs: []int64{}}
+	for _, msg := range s.messages {
+		if msg.ChannelID != req.ChannelID || msg.Customer.ExternalID != req.CustomerExternalID ||
+			msg.Read || msg.CreatedAt.After(req.Until) {
+			continue
+		}
+
+		msg.Read = true
+		resp.IDs = append(resp.IDs, msg.ID)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) ackMessage(w http.ResponseWriter, r *http.Request) {
+	var req v1.AckMessageRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ref v1.AckMessageRequestMessage
+	if req.Message != nil {
+		ref = *req.Message
+	}
+
+	msg := s.lookupMessage(w, req.Channel, ref.ID, ref.ExternalID)
+	if msg == nil {
+		return
+	}
+	if !msg.Outgoing {
+		writeErrors(w, http.StatusBadRequest, "Message was not sent to the transport")
+		return
+	}
+	if req.ExternalMessageID == "" && req.Error == nil {
+		writeErrors(w, http.StatusBadRequest, "Either external message ID or error is required")
+		return
+	}
+
+	if req.ExternalMessageID != "" {
+		msg.ExternalID = req.ExternalMessageID
+	}
+	s.acks = append(s.acks, req)
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) addReaction(w http.ResponseWriter, r *http.Request) {
+	var req v1.ReactionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, req.Channel, req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+
+	reactions := s.channels[req.Channel].Settings.Reactions
+	switch {
+	case req.Reaction == "":
+		writeErrors(w, http.StatusBadRequest, "Reaction is required")
+		return
+	case len(reactions.Dictionary) > 0 && !slices.Contains(reactions.Dictionary, req.Reaction):
+		writeErrors(w, http.StatusBadRequest, "Reaction is not allowed in the channel")
+		return
+	case slices.Contains(msg.Reactions, req.Reaction):
+	case reactions.MaxCount > 0 && len(msg.Reactions) >= int(reactions.MaxCount):
+		writeErrors(w, http.StatusBadRequest, "Too many reactions")
+		return
+	default:
+		msg.Reactions = append(msg.Reactions, req.Reaction)
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) deleteReaction(w http.ResponseWriter, r *http.Request) {
+	var req v1.ReactionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.lookupMessage(w, req.Channel, req.Message.ID, req.Message.ExternalID)
+	if msg == nil {
+		return
+	}
+
+	if req.Reaction == "" {
+		msg.Reactions = nil
+	} else {
+		msg.Reactions = slices.DeleteFunc(msg.Reactions, func(reaction string) bool {
+			return reaction == req.Reaction
+		})
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
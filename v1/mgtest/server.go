@@ -0,0 +1,204 @@
+// Package mgtest provides an in-process MessageGateway Transport API server for integration tests.
+//
+// The server keeps channels, messages, templates and files in memory, checks the transport token, validates
+// requests similarly to MessageGateway and can inject faults (e.g. 429 or 503 responses). It can also send
+// webhooks to the transport which makes it possible to test the whole message flow offline:
+//
+//	srv := mgtest.NewServer(mgtest.WithWebhookURL(transport.URL + "/webhook"))
+//	defer srv.Close()
+//
+//	client := srv.MgClient()
+//	ch, _, _ := client.ActivateTransportChannel(v1.Channel{Type: "telegram", Name: "@bot"})
+//	_, _, _ = client.Messages(v1.SendData{...})
+//
+//	resp, err := srv.SendMessage(context.Background(), v1.MessageWebhookData{
+//		ChannelID:      ch.ChannelID,
+//		ExternalChatID: "chat",
+//		Type:           v1.MsgTypeText,
+//		Content:        "Hello!",
+//	})
+package mgtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// DefaultToken is the transport token which is accepted by the server by default.
+const DefaultToken = "mgtest_token"
+
+const apiPrefix = "/api/transport/v1"
+
+// Server is the in-memory Transport API server.
+type Server struct {
+	*httptest.Server
+	// Token is the accepted transport token.
+	Token string
+
+	webhookURL    string
+	webhookClient *http.Client
+	webhookToken  string
+	webhookSecret []byte
+	now           func() time.Time
+
+	mu        sync.Mutex
+	channels  map[uint64]*channel
+	messages  []*Message
+	templates []*v1.Template
+	files     map[string]*file
+	acks      []v1.AckMessageRequest
+	faults    []*Fault
+	lastID    int64
+	webhookID uint64
+}
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithToken sets the accepted transport token.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.Token = token
+	}
+}
+
+// WithWebhookURL sets the transport URL which will receive the webhooks.
+func WithWebhookURL(url string) Option {
+	return func(s *Server) {
+		s.webhookURL = url
+	}
+}
+
+// WithWebhookToken sets the token which is sent with every webhook in the v1.DefaultWebhookTokenHeader,
+// so the webhooks are accepted by the v1.WebhookVerifier with the same Token.
+func WithWebhookToken(token string) Option {
+	return func(s *Server) {
+		s.webhookToken = token
+	}
+}
+
+// WithWebhookSecret sets the key which is used to sign every webhook body. The signature is sent
+// in the v1.DefaultWebhookSignatureHeader, see v1.WebhookSignature.
+func WithWebhookSecret(secret []byte) Option {
+	return func(s *Server) {
+		s.webhookSecret = secret
+	}
+}
+
+// WithWebhookClient sets the HTTP client which is used to send the webhooks.
+func WithWebhookClient(client *http.Client) Option {
+	return func(s *Server) {
+		s.webhookClient = client
+	}
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when finished.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		Token:         DefaultToken,
+		webhookClient: &http.Client{Timeout: time.Minute},
+		now:           time.Now,
+		channels:      map[uint64]*channel{},
+		files:         map[string]*file{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(s.handler())
+	return s
+}
+
+// MgClient returns the Transport API client which is configured to use this server.
+func (s *Server) MgClient() *v1.MgClient {
+	return v1.NewWithClient(s.URL, s.Token, s.Client())
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	routes := map[string]http.HandlerFunc{
+		"GET /channels":                          s.listChannels,
+		"POST /channels":                         s.activateChannel,
+		"PUT /channels/{id}":                     s.updateChannel,
+		"DELETE /channels/{id}":                  s.deactivateChannel,
+		"GET /templates":                         s.listTemplates,
+		"POST /channels/{id}/templates":          s.activateTemplate,
+		"PUT /channels/{id}/templates/{code}":    s.updateTemplate,
+		"DELETE /channels/{id}/templates/{code}": s.deactivateTemplate,
+		"POST /messages":                         s.sendMessage,
+		"PUT /messages":                          s.editMessage,
+		"DELETE /messages":                       s.deleteMessage,
+		"POST /messages/history":                 s.sendHistoryMessage,
+		"POST /messages/restore":                 s.restoreMessage,
+		"POST /messages/read":                    s.readMessage,
+		"POST /messages/read_until":              s.readUntil,
+		"POST /messages/ack":                     s.ackMessage,
+		"POST /messages/reaction":                s.addReaction,
+		"DELETE /messages/reaction":              s.deleteReaction,
+		"POST /files/upload":                     s.uploadFile,
+		"POST /files/upload_by_url":              s.uploadFileByURL,
+		"GET /files/{id}":                        s.getFile,
+	}
+
+	for pattern, handler := range routes {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+apiPrefix+path, handler)
+	}
+	mux.HandleFunc("GET /download/{id}", s.downloadFile)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, apiPrefix) {
+			if r.Header.Get("X-Transport-Token") != s.Token {
+				writeErrors(w, http.StatusUnauthorized, "Invalid transport token")
+				return
+			}
+			if s.serveFault(w, r) {
+				return
+			}
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, out interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func writeErrors(w http.ResponseWriter, status int, errors ...string) {
+	writeJSON(w, status, v1.MGErrors{Errors: errors})
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid channel ID")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,362 @@
+package mgtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+func activateChannel(t *testing.T, client *v1.MgClient, settings v1.ChannelSettings) uint64 {
+	resp, status, err := client.ActivateTransportChannel(v1.Channel{Type: "telegram", Name: "@bot", Settings: settings})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	return resp.ChannelID
+}
+
+func TestServer_InvalidToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	_, status, err := v1.NewWithClient(srv.URL, "invalid", srv.Client()).TransportChannels(v1.Channels{})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.True(t, v1.AsClientError(err).IsAuth())
+}
+
+func TestServer_Channels(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+
+	id := activateChannel(t, client, v1.ChannelSettings{})
+	second := activateChannel(t, client, v1.ChannelSettings{})
+
+	_, _, err := client.UpdateTransportChannel(v1.Channel{ID: id, Name: "@renamed"})
+	require.NoError(t, err)
+	_, _, err = client.DeactivateTransportChannel(second)
+	require.NoError(t, err)
+
+	channels, _, err := client.TransportChannels(v1.Channels{Active: true})
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.Equal(t, id, channels[0].ID)
+	assert.Equal(t, "@renamed", *channels[0].Name)
+	assert.Equal(t, "telegram", channels[0].Type)
+
+	_, status, err := client.DeactivateTransportChannel(second)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.True(t, v1.AsClientError(err).IsNotFound())
+
+	_, status, err = client.ActivateTransportChannel(v1.Channel{Name: "no type"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"Channel type is required"}, v1.AsClientError(err).Errors)
+}
+
+func TestServer_MessagesLifecycle(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+	channelID := activateChannel(t, client, v1.ChannelSettings{
+		Text:      v1.ChannelSettingsText{MaxCharsCount: 10},
+		Reactions: v1.Reactions{Dictionary: []string{"👍"}},
+	})
+
+	data := v1.SendData{
+		Message:        v1.Message{ExternalID: "ext-1", Type: v1.MsgTypeText, Text: "Hello"},
+		Customer:       v1.Customer{ExternalID: "customer"},
+		Channel:        channelID,
+		ExternalChatID: "chat",
+	}
+	sent, _, err := client.Messages(data)
+	require.NoError(t, err)
+	require.NotZero(t, sent.MessageID)
+
+	_, status, err := client.Messages(data)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"Message with such external ID already exists"}, v1.AsClientError(err).Errors)
+
+	_, _, err = client.UpdateMessages(v1.EditMessageRequest{
+		Channel: channelID,
+		Message: v1.EditMessageRequestMessage{ExternalID: "ext-1", Text: "Edited"},
+	})
+	require.NoError(t, err)
+
+	_, err = client.AddMessageReaction(v1.ReactionRequest{
+		Channel:  channelID,
+		Message:  v1.ReactionMessageReference{ExternalID: "ext-1"},
+		Reaction: "👍",
+	})
+	require.NoError(t, err)
+
+	_, _, err = client.MarkMessageRead(v1.MarkMessageReadRequest{
+		ChannelID: channelID,
+		Message:   v1.MarkMessageReadRequestMessage{ExternalID: "ext-1"},
+	})
+	require.NoError(t, err)
+
+	_, _, err = client.DeleteMessage(v1.DeleteData{Channel: channelID, Message: v1.Message{ExternalID: "ext-1"}})
+	require.NoError(t, err)
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Edited", messages[0].Text)
+	assert.Equal(t, []string{"👍"}, messages[0].Reactions)
+	assert.True(t, messages[0].Read)
+	assert.True(t, messages[0].Deleted)
+
+	_, _, err = client.RestoreMessage(v1.RestoreMessageRequest{
+		ChannelID: int64(channelID),
+		Message:   v1.RestoreMessageRequestMessage{ExternalID: "ext-1"},
+	})
+	require.NoError(t, err)
+	assert.False(t, srv.Messages()[0].Deleted)
+}
+
+func TestServer_MessagesValidation(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+	channelID := activateChannel(t, client, v1.ChannelSettings{
+		Text:  v1.ChannelSettingsText{MaxCharsCount: 5},
+		Image: v1.ChannelSettingsFilesBase{Creating: v1.ChannelFeatureNone},
+	})
+
+	_, status, err := client.Messages(v1.SendData{
+		Message: v1.Message{Type: v1.MsgTypeText, Text: "Too long text"},
+		Channel: channelID,
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{
+		"External chat ID is required",
+		"Customer external ID is required",
+		"Message text is too long",
+	}, v1.AsClientError(err).Errors)
+
+	_, _, err = client.Messages(v1.SendData{
+		Message:        v1.Message{Type: v1.MsgTypeImage, Items: []v1.Item{{ID: "file"}}},
+		Customer:       v1.Customer{ExternalID: "customer"},
+		Channel:        channelID,
+		ExternalChatID: "chat",
+	})
+	assert.Equal(t, []string{"Channel does not support messages of type image"}, v1.AsClientError(err).Errors)
+
+	_, status, err = client.UpdateMessages(v1.EditMessageRequest{
+		Channel: channelID,
+		Message: v1.EditMessageRequestMessage{ExternalID: "unknown", Text: "text"},
+	})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.True(t, v1.AsClientError(err).IsNotFound())
+}
+
+func TestServer_ReadUntil(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+	channelID := activateChannel(t, client, v1.ChannelSettings{})
+
+	for i, text := range []string{"first", "second"} {
+		createdAt := time.Date(2024, 1, 1, i, 0, 0, 0, time.UTC)
+		_, _, err := client.MessagesHistory(v1.SendHistoryMessageRequest{
+			Message:        v1.SendMessageRequestMessage{Type: v1.MsgTypeText, Text: text, CreatedAt: &createdAt},
+			ChannelID:      channelID,
+			ExternalChatID: "chat",
+			Customer:       &v1.Customer{ExternalID: "customer"},
+		})
+		require.NoError(t, err)
+	}
+
+	resp, _, err := client.ReadUntil(v1.MarkMessagesReadUntilRequest{
+		CustomerExternalID: "customer",
+		ChannelID:          channelID,
+		Until:              time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.IDs, 1)
+	assert.True(t, srv.Messages()[0].History)
+}
+
+func TestServer_Templates(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+	channelID := activateChannel(t, client, v1.ChannelSettings{})
+
+	_, err := client.ActivateTemplate(channelID, v1.ActivateTemplateRequest{
+		UpdateTemplateRequest: v1.UpdateTemplateRequest{Name: "Greeting", Body: "Hello!"},
+		Code:                  "greeting#ru",
+		Type:                  v1.TemplateTypeText,
+	})
+	require.NoError(t, err)
+
+	_, err = client.UpdateTemplate(channelID, "greeting#ru", v1.UpdateTemplateRequest{Name: "Greeting", Body: "Hi!"})
+	require.NoError(t, err)
+
+	templates, _, err := client.TransportTemplates()
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "Hi!", templates[0].Body)
+
+	_, err = client.DeactivateTemplate(channelID, "greeting#ru")
+	require.NoError(t, err)
+	assert.Empty(t, srv.Templates())
+
+	status, err := client.DeactivateTemplate(channelID, "greeting#ru")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Error(t, err)
+}
+
+func TestServer_Files(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient()
+
+	uploaded, _, err := client.UploadFile(bytes.NewReader([]byte("plain text file")))
+	require.NoError(t, err)
+	assert.Equal(t, v1.MsgTypeFile, uploaded.Type)
+	assert.Equal(t, "text/plain", uploaded.MimeType)
+
+	file, _, err := client.GetFile(uploaded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 15, file.Size)
+
+	resp, err := srv.Client().Get(file.Url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	byURL, _, err := client.UploadFileByURL(v1.UploadFileByUrlRequest{Url: "https://example.com/image.png"})
+	require.NoError(t, err)
+	assert.Equal(t, v1.MsgTypeImage, byURL.Type)
+
+	_, status, err := client.GetFile("unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Error(t, err)
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	client := srv.MgClient().WithRetryPolicy(&v1.BackoffRetryPolicy{MaxAttempts: 3})
+
+	srv.InjectFault(Fault{Method: http.MethodGet, Path: "/channels", Status: http.StatusTooManyRequests, Times: 2})
+	_, status, err := client.TransportChannels(v1.Channels{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	srv.InjectFault(Fault{Status: http.StatusServiceUnavailable, RetryAfter: time.Minute})
+	_, status, err = srv.MgClient().TransportChannels(v1.Channels{})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.True(t, v1.AsClientError(err).IsTemporary())
+
+	srv.ClearFaults()
+	_, _, err = client.TransportChannels(v1.Channels{})
+	require.NoError(t, err)
+}
+
+func TestServer_FaultRetryAfter(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	for delay, expected := range map[time.Duration]string{
+		500 * time.Millisecond:  "1",
+		1500 * time.Millisecond: "2",
+		time.Minute:             "60",
+		0:                       "",
+		-time.Second:            "",
+	} {
+		srv.InjectFault(Fault{Status: http.StatusTooManyRequests, RetryAfter: delay, Times: 1})
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/transport/v1/channels", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Transport-Token", srv.Token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, delay)
+		assert.Equal(t, expected, resp.Header.Get("Retry-After"), delay)
+	}
+}
+
+func TestServer_SendMessage(t *testing.T) {
+	var received v1.WebhookRequest
+	transport := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_ = json.NewEncoder(w).Encode(v1.WebhookMessageSentResponse{Async: true})
+	}))
+	defer transport.Close()
+
+	srv := NewServer(WithWebhookURL(transport.URL))
+	defer srv.Close()
+	client := srv.MgClient()
+	channelID := activateChannel(t, client, v1.ChannelSettings{})
+
+	resp, err := srv.SendMessage(context.Background(), v1.MessageWebhookData{
+		ChannelID:      channelID,
+		ExternalChatID: "chat",
+		Type:           v1.MsgTypeText,
+		Content:        "Hello!",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Async)
+	assert.Equal(t, v1.MessageSendWebhookType, received.Type)
+	assert.NotZero(t, received.Meta.ID)
+
+	data := received.MessageWebhookData()
+	assert.Equal(t, "Hello!", data.Content)
+
+	_, err = client.AckMessage(v1.AckMessageRequest{
+		ExternalMessageID: "external",
+		Channel:           channelID,
+		Message:           &v1.AckMessageRequestMessage{ID: &data.ID},
+	})
+	require.NoError(t, err)
+	assert.Len(t, srv.Acks(), 1)
+	assert.Equal(t, "external", srv.Messages()[0].ExternalID)
+}
+
+func TestServer_SendWebhookVerified(t *testing.T) {
+	var received []v1.TemplateDeleteWebhookData
+	verifier := &v1.WebhookVerifier{Token: "webhook_token", Secret: []byte("key"), Nonces: v1.NewMemoryNonceStore()}
+	transport := httptest.NewServer(verifier.Handler(&v1.WebhookHandler{
+		OnTemplateDelete: func(_ context.Context, data v1.TemplateDeleteWebhookData) error {
+			received = append(received, data)
+			return nil
+		},
+	}))
+	defer transport.Close()
+
+	srv := NewServer(WithWebhookURL(transport.URL), WithWebhookToken("webhook_token"), WithWebhookSecret([]byte("key")))
+	defer srv.Close()
+
+	resp, err := srv.SendWebhook(context.Background(), v1.TemplateDeleteWebhookType, v1.TemplateDeleteWebhookData{Code: "code"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, string(resp.Body))
+	require.Len(t, received, 1)
+	assert.Equal(t, "code", received[0].Code)
+
+	unsigned := NewServer(WithWebhookURL(transport.URL), WithWebhookToken("webhook_token"))
+	defer unsigned.Close()
+
+	resp, err = unsigned.SendWebhook(context.Background(), v1.TemplateDeleteWebhookType, v1.TemplateDeleteWebhookData{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(t, received, 1)
+}
+
+func TestServer_SendWebhookWithoutURL(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	_, err := srv.SendWebhook(context.Background(), v1.TemplateDeleteWebhookType, v1.TemplateDeleteWebhookData{})
+	assert.True(t, errors.Is(err, ErrNoWebhookURL))
+}
@@ -0,0 +1,141 @@
+package mgtest
+
+import (
+	"net/http"
+	"slices"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// Templates returns all templates known to the server.
+func (s *Server) Templates() []v1.Template {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates := make([]v1.Template, 0, len(s.templates))
+	for _, tpl := range s.templates {
+		templates = append(templates, *tpl)
+	}
+
+	return templates
+}
+
+// template returns the index of the template. The caller must hold the lock.
+func (s *Server) template(channelID uint64, code string) int {
+	return slices.IndexFunc(s.templates, func(tpl *v1.Template) bool {
+		return tpl.ChannelID == channelID && tpl.Code == code
+	})
+}
+
+func (s *Server) listTemplates(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Templates())
+}
+
+func (s *Server) activateTemplate(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req v1.ActivateTemplateRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []string
+	if ch, msg := s.activeChannel(channelID); ch == nil {
+		errs = append(errs, msg)
+	}
+	if req.Code == "" {
+		errs = append(errs, "Template code is required")
+	} else if s.template(channelID, req.Code) != -1 {
+		errs = append(errs, "Template with such code already exists")
+	}
+	if req.Name == "" {
+		errs = append(errs, "Template name is required")
+	}
+	if req.Type == 0 {
+		errs = append(errs, "Template type is required")
+	}
+	if len(errs) > 0 {
+		writeErrors(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	tpl := &v1.Template{
+		ID:        s.nextID(),
+		Code:      req.Code,
+		ChannelID: channelID,
+		Enabled:   true,
+		Type:      req.Type,
+	}
+	applyTemplateUpdate(tpl, req.UpdateTemplateRequest)
+	s.templates = append(s.templates, tpl)
+
+	writeJSON(w, http.StatusCreated, struct{}{})
+}
+
+func (s *Server) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req v1.UpdateTemplateRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.template(channelID, r.PathValue("code"))
+	if i == -1 {
+		writeErrors(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	if req.Name == "" {
+		writeErrors(w, http.StatusBadRequest, "Template name is required")
+		return
+	}
+
+	applyTemplateUpdate(s.templates[i], req)
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) deactivateTemplate(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.template(channelID, r.PathValue("code"))
+	if i == -1 {
+		writeErrors(w, http.StatusNotFound, "Template not found")
+		return
+	}
+
+	s.templates = slices.Delete(s.templates, i, i+1)
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func applyTemplateUpdate(tpl *v1.Template, req v1.UpdateTemplateRequest) {
+	tpl.Name = req.Name
+	tpl.Template = req.Template
+	tpl.Body = req.Body
+	tpl.Lang = req.Lang
+	tpl.Category = req.Category
+	tpl.Example = req.Example
+	tpl.VerificationStatus = req.VerificationStatus
+	tpl.Quality = req.Quality
+	tpl.RejectionReason = req.RejectionReason
+	tpl.Header = req.Header
+	tpl.Footer = req.Footer
+	tpl.Buttons = req.Buttons
+}
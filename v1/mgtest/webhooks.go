@@ -0,0 +1,120 @@
+package mgtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// ErrNoWebhookURL is returned when the webhook is sent, but the transport URL is not configured.
+var ErrNoWebhookURL = errors.New("mgtest: webhook URL is not configured")
+
+// WebhookResponse is the transport response to the webhook.
+type WebhookResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode unmarshals the response body into v.
+func (r *WebhookResponse) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// SendWebhook sends the webhook of the provided type to the transport. Data is marshaled into the webhook data.
+// The response is returned regardless of its status code. The webhook is sent with the token and the signature
+// set by WithWebhookToken and WithWebhookSecret.
+func (s *Server) SendWebhook(
+	ctx context.Context, webhookType v1.WebhookType, data interface{}) (*WebhookResponse, error) {
+	if s.webhookURL == "" {
+		return nil, ErrNoWebhookURL
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.webhookID++
+	meta := v1.TransportRequestMeta{ID: s.webhookID, Timestamp: s.now().Unix()}
+	s.mu.Unlock()
+
+	body, err := json.Marshal(v1.WebhookRequest{Type: webhookType, Meta: meta, Data: raw})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.webhookToken != "" {
+		req.Header.Set(v1.DefaultWebhookTokenHeader, s.webhookToken)
+	}
+	if len(s.webhookSecret) > 0 {
+		req.Header.Set(v1.DefaultWebhookSignatureHeader, v1.WebhookSignature(s.webhookSecret, body))
+	}
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
+
+// SendMessage stores the outgoing message and sends the message_sent webhook for it to the transport.
+// Message ID is generated if it's not set. The external message ID from the synchronous transport response
+// is stored with the message, asynchronous responses are expected to be confirmed via AckMessage.
+func (s *Server) SendMessage(ctx context.Context, data v1.MessageWebhookData) (v1.WebhookMessageSentResponse, error) {
+	var result v1.WebhookMessageSentResponse
+
+	s.mu.Lock()
+	if data.ID == 0 {
+		data.ID = s.nextID()
+	}
+	msg := &Message{
+		ID:             data.ID,
+		ChannelID:      data.ChannelID,
+		ExternalChatID: data.ExternalChatID,
+		Type:           data.Type,
+		Text:           data.Content,
+		Outgoing:       true,
+		CreatedAt:      s.now(),
+	}
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	resp, err := s.SendWebhook(ctx, v1.MessageSendWebhookType, data)
+	if err != nil {
+		return result, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("mgtest: unexpected webhook response status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if err := resp.Decode(&result); err != nil {
+		return result, err
+	}
+
+	if !result.Async && result.ExternalMessageID != "" {
+		s.mu.Lock()
+		msg.ExternalID = result.ExternalMessageID
+		s.mu.Unlock()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// APIRequest is a single attempt of the Transport API call. It is passed through the middleware chain.
+type APIRequest struct {
+	// Method is an HTTP method of the request.
+	Method string
+	// Route is a route template, e.g. "/channels/{id}".
+	Route string
+	// Path is a resolved route with the query string, e.g. "/channels/1".
+	Path string
+	// URL is the full request URL.
+	URL string
+	// Header contains the request headers. Middlewares can modify them.
+	Header http.Header
+	// Attempt is the number of the attempt, starting from 1.
+	Attempt int
+
+	body        *requestBody
+	rateLimited int
+}
+
+// Body returns the request body from the beginning. It can be called more than once.
+func (r *APIRequest) Body() (io.ReadCloser, error) {
+	return r.body.reader()
+}
+
+// APIResponse is the Transport API response. It is passed back through the middleware chain.
+type APIResponse struct {
+	// StatusCode of the response.
+	StatusCode int
+	// Header contains the response headers.
+	Header http.Header
+	// Body is the response body. It is limited by LimitResponse.
+	Body []byte
+	// Truncated is true if the response body was larger than LimitResponse.
+	Truncated bool
+
+	request *APIRequest
+}
+
+// Doer performs the Transport API request.
+type Doer interface {
+	Do(ctx context.Context, req *APIRequest) (*APIResponse, error)
+}
+
+// DoerFunc is an adapter to use ordinary functions as Doer.
+type DoerFunc func(ctx context.Context, req *APIRequest) (*APIResponse, error)
+
+// Do calls f(ctx, req).
+func (f DoerFunc) Do(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+	return f(ctx, req)
+}
+
+// Middleware wraps the Doer. It can inspect or modify the request before calling the next Doer
+// and inspect the response or the error after it.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware appends provided middlewares to the Client. The first middleware is the outermost one.
+// Middlewares are called for every request attempt, including retries.
+//
+// Example:
+//
+//	client := New("https://message-gateway.url", "cb8ccf05e38a47543ad8477d4999be73bff503ea6")
+//	client.WithMiddleware(func(next Doer) Doer {
+//		return DoerFunc(func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+//			start := time.Now()
+//			resp, err := next.Do(ctx, req)
+//			log.Printf("%s %s took %s", req.Method, req.Route, time.Since(start))
+//			return resp, err
+//		})
+//	})
+func (c *MgClient) WithMiddleware(middlewares ...Middleware) *MgClient {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// doer returns the middleware chain which ends with the HTTP request.
+func (c *MgClient) doer() Doer {
+	var doer Doer = DoerFunc(c.send)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		doer = c.middlewares[i](doer)
+	}
+
+	return doer
+}
+
+// send performs the HTTP request and reads the response.
+func (c *MgClient) send(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+	body, err := req.Body()
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.GetBody = req.body.reader
+	httpReq.ContentLength = req.body.size()
+	httpReq.Header = req.Header.Clone()
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	data, truncated, err := readLimitedResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+		Truncated:  truncated,
+	}, nil
+}
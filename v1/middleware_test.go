@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestMgClient_WithMiddleware(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://mg-test.retailcrm.pro").
+		Put("/api/transport/v1/channels/1/templates/code").
+		MatchHeader("X-Signature", "signed").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{})
+
+	var calls []string
+	var seen *APIRequest
+	var seenBody string
+	var seenStatus int
+
+	c := New("https://mg-test.retailcrm.pro", "mg_token").WithMiddleware(
+		func(next Doer) Doer {
+			return DoerFunc(func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				calls = append(calls, "outer")
+				resp, err := next.Do(ctx, req)
+				if resp != nil {
+					seenStatus = resp.StatusCode
+				}
+				return resp, err
+			})
+		},
+		func(next Doer) Doer {
+			return DoerFunc(func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				calls = append(calls, "inner")
+				body, err := req.Body()
+				require.NoError(t, err)
+				data, _ := io.ReadAll(body)
+				seen, seenBody = req, string(data)
+				req.Header.Set("X-Signature", "signed")
+				return next.Do(ctx, req)
+			})
+		},
+	)
+
+	status, err := c.UpdateTemplate(1, "code", UpdateTemplateRequest{Name: "name"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"outer", "inner"}, calls)
+	assert.Equal(t, http.MethodPut, seen.Method)
+	assert.Equal(t, "/channels/{id}/templates/{code}", seen.Route)
+	assert.Equal(t, "/channels/1/templates/code", seen.Path)
+	assert.Equal(t, 1, seen.Attempt)
+	assert.Contains(t, seenBody, `"name":"name"`)
+	assert.Equal(t, http.StatusOK, seenStatus)
+	assert.True(t, gock.IsDone())
+}
+
+func TestMgClient_WithMiddleware_Retries(t *testing.T) {
+	policy := NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+
+	var attempts []int
+	c := New("https://mg-test.retailcrm.pro", "mg_token").
+		WithRetryPolicy(policy).
+		WithMiddleware(func(next Doer) Doer {
+			return DoerFunc(func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				attempts = append(attempts, req.Attempt)
+				if req.Attempt == 1 {
+					return &APIResponse{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}, nil
+				}
+				return &APIResponse{StatusCode: http.StatusOK, Body: []byte(`[{"id":1}]`)}, nil
+			})
+		})
+
+	data, status, err := c.TransportChannels(Channels{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, data, 1)
+	assert.Equal(t, []int{1, 2}, attempts)
+}
+
+func TestMgClient_WithMiddleware_Error(t *testing.T) {
+	c := New("https://mg-test.retailcrm.pro", "mg_token").
+		WithMiddleware(func(next Doer) Doer {
+			return DoerFunc(func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				return nil, errors.New("chaos")
+			})
+		})
+
+	_, status, err := c.GetFile("file")
+	require.Error(t, err)
+	assert.Equal(t, 0, status)
+	assert.Equal(t, "http client error: chaos", err.Error())
+}
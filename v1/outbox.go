@@ -0,0 +1,658 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOutboxAttempts  = 10
+	defaultOutboxBaseDelay = time.Second
+	defaultOutboxMaxDelay  = time.Minute
+)
+
+// ErrOutboxEntryNotFound is returned if the dead letter with the provided sequence number doesn't exist.
+var ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+
+// OutboxOp is the type of the operation stored in the Outbox.
+type OutboxOp string
+
+const (
+	OutboxMessages               OutboxOp = "messages"
+	OutboxMessagesHistory        OutboxOp = "messages_history"
+	OutboxUpdateMessages         OutboxOp = "update_messages"
+	OutboxDeleteMessage          OutboxOp = "delete_message"
+	OutboxAddMessageReaction     OutboxOp = "add_message_reaction"
+	OutboxDeleteMessagesReaction OutboxOp = "delete_messages_reaction"
+	OutboxMarkMessageRead        OutboxOp = "mark_message_read"
+)
+
+// OutboxEntry is the operation stored in the Outbox.
+type OutboxEntry struct {
+	Seq            uint64          `json:"seq"`
+	Op             OutboxOp        `json:"op"`
+	ChannelID      uint64          `json:"channel_id"`
+	ExternalChatID string          `json:"external_chat_id"`
+	Request        json.RawMessage `json:"request"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// Attempts is the number of the failed delivery attempts since the outbox was opened.
+	Attempts int `json:"-"`
+	// LastError is the error of the last failed delivery attempt.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// OutboxStats contains the Outbox counters. Delivered, Retried and Dead are counted since the outbox was opened.
+type OutboxStats struct {
+	Pending     int
+	Chats       int
+	DeadLetters int
+	Delivered   uint64
+	Retried     uint64
+	Dead        uint64
+	// Corrupted is the number of the log records which were skipped because they could not be decoded
+	// when the outbox was opened.
+	Corrupted uint64
+}
+
+// OutboxConfig configures the Outbox.
+type OutboxConfig struct {
+	// Workers is the number of chats which are delivered concurrently. Defaults to 1.
+	Workers int
+	// Retry decides whether the failed operation should be retried or moved to the dead letters.
+	// Defaults to the BackoffRetryPolicy with 10 attempts and delays up to one minute which retries all errors.
+	Retry RetryPolicy
+}
+
+type outboxRecord struct {
+	Kind  string       `json:"kind"`
+	Entry *OutboxEntry `json:"entry,omitempty"`
+	Seq   uint64       `json:"seq,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+const (
+	outboxRecordAdd  = "add"
+	outboxRecordDone = "done"
+	outboxRecordDead = "dead"
+)
+
+type chatKey struct {
+	channelID      uint64
+	externalChatID string
+}
+
+type outboxChat struct {
+	entries   []*OutboxEntry
+	busy      bool
+	notBefore time.Time
+}
+
+// Outbox persists the outgoing operations in the append-only log and delivers them to MessageGateway
+// in the background. Operations of the same chat (channel and external chat ID) are delivered in order,
+// different chats are delivered concurrently. Failed operations are retried and moved to the dead letters
+// when the retry policy gives up.
+//
+// The log is compacted when the outbox is opened. Delivery is at-least-once: the operation which was sent
+// right before the crash is sent again after the restart.
+//
+// Example:
+//
+//	outbox, err := NewOutbox(client, "/var/lib/transport/outbox.log", OutboxConfig{Workers: 4})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer outbox.Close()
+//	go outbox.Run(ctx)
+//
+//	_, err = outbox.Messages(SendData{...})
+type Outbox struct {
+	client  Client
+	retry   RetryPolicy
+	workers int
+	path    string
+
+	mu     sync.Mutex
+	file   *os.File
+	seq    uint64
+	chats  map[chatKey]*outboxChat
+	dead   []*OutboxEntry
+	stats  OutboxStats
+	notify chan struct{}
+}
+
+// NewOutbox opens the outbox log at the path, restores the pending operations and compacts the log.
+func NewOutbox(client Client, path string, config OutboxConfig) (*Outbox, error) {
+	o := &Outbox{
+		client:  client,
+		retry:   config.Retry,
+		workers: config.Workers,
+		path:    path,
+		chats:   map[chatKey]*outboxChat{},
+		notify:  make(chan struct{}, 1),
+	}
+	if o.retry == nil {
+		o.retry = &BackoffRetryPolicy{
+			MaxAttempts:        defaultOutboxAttempts,
+			BaseDelay:          defaultOutboxBaseDelay,
+			MaxDelay:           defaultOutboxMaxDelay,
+			Jitter:             defaultRetryJitter,
+			RetryNonIdempotent: true,
+		}
+	}
+	if o.workers <= 0 {
+		o.workers = 1
+	}
+
+	if err := o.restore(); err != nil {
+		return nil, err
+	}
+	if err := o.compact(); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Messages stores the message which will be sent with MgClient.Messages.
+func (o *Outbox) Messages(request SendData) (uint64, error) {
+	return o.add(OutboxMessages, request.Channel, request.ExternalChatID, request)
+}
+
+// MessagesHistory stores the message which will be sent with MgClient.MessagesHistory.
+func (o *Outbox) MessagesHistory(request SendHistoryMessageRequest) (uint64, error) {
+	return o.add(OutboxMessagesHistory, request.ChannelID, request.ExternalChatID, request)
+}
+
+// UpdateMessages stores the edit which will be sent with MgClient.UpdateMessages.
+// The external chat ID is used to order the operation with other operations of the chat.
+func (o *Outbox) UpdateMessages(externalChatID string, request EditMessageRequest) (uint64, error) {
+	return o.add(OutboxUpdateMessages, request.Channel, externalChatID, request)
+}
+
+// DeleteMessage stores the removal which will be sent with MgClient.DeleteMessage.
+// The external chat ID is used to order the operation with other operations of the chat.
+func (o *Outbox) DeleteMessage(externalChatID string, request DeleteData) (uint64, error) {
+	return o.add(OutboxDeleteMessage, request.Channel, externalChatID, request)
+}
+
+// AddMessageReaction stores the reaction which will be sent with MgClient.AddMessageReaction.
+// The external chat ID is used to order the operation with other operations of the chat.
+func (o *Outbox) AddMessageReaction(externalChatID string, request ReactionRequest) (uint64, error) {
+	return o.add(OutboxAddMessageReaction, request.Channel, externalChatID, request)
+}
+
+// DeleteMessagesReaction stores the reaction removal which will be sent with MgClient.DeleteMessagesReaction.
+// The external chat ID is used to order the operation with other operations of the chat.
+func (o *Outbox) DeleteMessagesReaction(externalChatID string, request ReactionRequest) (uint64, error) {
+	return o.add(OutboxDeleteMessagesReaction, request.Channel, externalChatID, request)
+}
+
+// MarkMessageRead stores the read event which will be sent with MgClient.MarkMessageRead.
+// The external chat ID is used to order the operation with other operations of the chat.
+func (o *Outbox) MarkMessageRead(externalChatID string, request MarkMessageReadRequest) (uint64, error) {
+	return o.add(OutboxMarkMessageRead, request.ChannelID, externalChatID, request)
+}
+
+// Stats returns the outbox counters.
+func (o *Outbox) Stats() OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := o.stats
+	stats.Chats = len(o.chats)
+	stats.DeadLetters = len(o.dead)
+	for _, chat := range o.chats {
+		stats.Pending += len(chat.entries)
+	}
+
+	return stats
+}
+
+// DeadLetters returns the operations which could not be delivered.
+func (o *Outbox) DeadLetters() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries := make([]OutboxEntry, 0, len(o.dead))
+	for _, entry := range o.dead {
+		entries = append(entries, *entry)
+	}
+
+	return entries
+}
+
+// Requeue moves the dead letter to the end of its chat queue.
+func (o *Outbox) Requeue(seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	i := slices.IndexFunc(o.dead, func(entry *OutboxEntry) bool { return entry.Seq == seq })
+	if i == -1 {
+		return ErrOutboxEntryNotFound
+	}
+
+	entry := *o.dead[i]
+	o.seq++
+	entry.Seq = o.seq
+	entry.Attempts = 0
+	entry.LastError = ""
+
+	err := o.append(outboxRecord{Kind: outboxRecordAdd, Entry: &entry}, outboxRecord{Kind: outboxRecordDone, Seq: seq})
+	if err != nil {
+		return err
+	}
+
+	o.dead = slices.Delete(o.dead, i, i+1)
+	o.enqueue(&entry)
+	return nil
+}
+
+// DropDeadLetter removes the dead letter.
+func (o *Outbox) DropDeadLetter(seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	i := slices.IndexFunc(o.dead, func(entry *OutboxEntry) bool { return entry.Seq == seq })
+	if i == -1 {
+		return ErrOutboxEntryNotFound
+	}
+	if err := o.append(outboxRecord{Kind: outboxRecordDone, Seq: seq}); err != nil {
+		return err
+	}
+
+	o.dead = slices.Delete(o.dead, i, i+1)
+	return nil
+}
+
+// Close closes the outbox log. Run must be stopped before closing the outbox.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.file.Close()
+}
+
+// Run delivers the operations until the context is done.
+func (o *Outbox) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (o *Outbox) add(op OutboxOp, channelID uint64, externalChatID string, request interface{}) (uint64, error) {
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry := &OutboxEntry{
+		Seq:            o.seq + 1,
+		Op:             op,
+		ChannelID:      channelID,
+		ExternalChatID: externalChatID,
+		Request:        raw,
+		CreatedAt:      time.Now(),
+	}
+	if err := o.append(outboxRecord{Kind: outboxRecordAdd, Entry: entry}); err != nil {
+		return 0, err
+	}
+
+	o.seq++
+	o.enqueue(entry)
+	return entry.Seq, nil
+}
+
+// enqueue adds the entry to its chat queue. The caller must hold the lock.
+func (o *Outbox) enqueue(entry *OutboxEntry) {
+	key := chatKey{entry.ChannelID, entry.ExternalChatID}
+	chat, ok := o.chats[key]
+	if !ok {
+		chat = &outboxChat{}
+		o.chats[key] = chat
+	}
+
+	chat.entries = append(chat.entries, entry)
+	o.signal()
+}
+
+// signal wakes up one of the waiting workers. The caller must hold the lock.
+func (o *Outbox) signal() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Outbox) work(ctx context.Context) {
+	for {
+		key, chat, entry, wait := o.next()
+		if chat == nil {
+			if sleepUntilNotified(ctx, o.notify, wait) != nil {
+				return
+			}
+			continue
+		}
+
+		err := o.deliver(ctx, entry)
+		if ctx.Err() != nil {
+			o.release(key, chat)
+			return
+		}
+
+		o.complete(key, chat, entry, err)
+	}
+}
+
+// next returns the chat which is ready for the delivery with its first entry or the time to wait for it.
+func (o *Outbox) next() (chatKey, *outboxChat, *OutboxEntry, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(-1)
+	for key, chat := range o.chats {
+		if chat.busy || len(chat.entries) == 0 {
+			continue
+		}
+		if delay := chat.notBefore.Sub(now); delay > 0 {
+			if wait < 0 || delay < wait {
+				wait = delay
+			}
+			continue
+		}
+
+		chat.busy = true
+		// Other workers may pick the remaining chats.
+		o.signal()
+		return key, chat, chat.entries[0], 0
+	}
+
+	return chatKey{}, nil, nil, wait
+}
+
+func (o *Outbox) release(key chatKey, chat *outboxChat) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	chat.busy = false
+	if len(chat.entries) == 0 {
+		delete(o.chats, key)
+	}
+	o.signal()
+}
+
+func (o *Outbox) complete(key chatKey, chat *outboxChat, entry *OutboxEntry, err error) {
+	o.mu.Lock()
+	defer func() {
+		chat.busy = false
+		if len(chat.entries) == 0 {
+			delete(o.chats, key)
+		}
+		o.signal()
+		o.mu.Unlock()
+	}()
+
+	if err == nil {
+		if o.append(outboxRecord{Kind: outboxRecordDone, Seq: entry.Seq}) == nil {
+			chat.entries = chat.entries[1:]
+			o.stats.Delivered++
+		}
+		return
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	if delay, ok := o.retry.NextRetry(outboxRetryAttempt(entry, err)); ok {
+		chat.notBefore = time.Now().Add(delay)
+		o.stats.Retried++
+		return
+	}
+
+	if o.append(outboxRecord{Kind: outboxRecordDead, Seq: entry.Seq, Error: entry.LastError}) == nil {
+		chat.entries = chat.entries[1:]
+		o.dead = append(o.dead, entry)
+		o.stats.Dead++
+	}
+}
+
+func outboxRetryAttempt(entry *OutboxEntry, err error) RetryAttempt {
+	attempt := RetryAttempt{Number: entry.Attempts, Method: http.MethodPost, Err: err}
+	switch entry.Op {
+	case OutboxMessages:
+		attempt.Route = "/messages"
+	case OutboxMessagesHistory:
+		attempt.Route = "/messages/history"
+	case OutboxUpdateMessages:
+		attempt.Method, attempt.Route = http.MethodPut, "/messages"
+	case OutboxDeleteMessage:
+		attempt.Method, attempt.Route = http.MethodDelete, "/messages"
+	case OutboxAddMessageReaction:
+		attempt.Route = "/messages/reaction"
+	case OutboxDeleteMessagesReaction:
+		attempt.Method, attempt.Route = http.MethodDelete, "/messages/reaction"
+	case OutboxMarkMessageRead:
+		attempt.Route = "/messages/read"
+	}
+
+	if clientErr := AsClientError(err); clientErr != nil && clientErr.BaseError == nil {
+		attempt.StatusCode = clientErr.StatusCode
+		attempt.Err = nil
+	}
+
+	return attempt
+}
+
+func (o *Outbox) deliver(ctx context.Context, entry *OutboxEntry) error {
+	switch entry.Op {
+	case OutboxMessages:
+		return deliverOutbox(ctx, entry, o.client.MessagesContext)
+	case OutboxMessagesHistory:
+		return deliverOutbox(ctx, entry, o.client.MessagesHistoryContext)
+	case OutboxUpdateMessages:
+		return deliverOutbox(ctx, entry, o.client.UpdateMessagesContext)
+	case OutboxDeleteMessage:
+		return deliverOutbox(ctx, entry, o.client.DeleteMessageContext)
+	case OutboxAddMessageReaction:
+		return deliverOutbox(ctx, entry, func(ctx context.Context, r ReactionRequest) (struct{}, int, error) {
+			status, err := o.client.AddMessageReactionContext(ctx, r)
+			return struct{}{}, status, err
+		})
+	case OutboxDeleteMessagesReaction:
+		return deliverOutbox(ctx, entry, func(ctx context.Context, r ReactionRequest) (struct{}, int, error) {
+			status, err := o.client.DeleteMessagesReactionContext(ctx, r)
+			return struct{}{}, status, err
+		})
+	case OutboxMarkMessageRead:
+		return deliverOutbox(ctx, entry, o.client.MarkMessageReadContext)
+	}
+
+	return fmt.Errorf("unknown outbox operation: %s", entry.Op)
+}
+
+func deliverOutbox[Req, Resp any](
+	ctx context.Context, entry *OutboxEntry, send func(context.Context, Req) (Resp, int, error)) error {
+	var request Req
+	if err := json.Unmarshal(entry.Request, &request); err != nil {
+		return err
+	}
+
+	_, _, err := send(ctx, request)
+	return err
+}
+
+// append writes the records to the log. The caller must hold the lock.
+func (o *Outbox) append(records ...outboxRecord) error {
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if _, err := o.file.Write(data); err != nil {
+		return err
+	}
+
+	return o.file.Sync()
+}
+
+func (o *Outbox) restore() error {
+	file, err := os.Open(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var entries []*OutboxEntry
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// The last line is incomplete if the process has crashed while writing it.
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		record, ok := decodeOutboxRecord(line)
+		if !ok {
+			o.stats.Corrupted++
+			continue
+		}
+
+		entries = o.apply(entries, record)
+	}
+
+	for _, entry := range entries {
+		o.enqueue(entry)
+	}
+
+	return nil
+}
+
+// decodeOutboxRecord decodes the log line. If the write of the previous record has failed halfway,
+// the next record is appended right after the incomplete one, so the last record in the line is decoded.
+func decodeOutboxRecord(line []byte) (outboxRecord, bool) {
+	var record outboxRecord
+	if err := json.Unmarshal(line, &record); err == nil {
+		return record, true
+	}
+
+	start := bytes.LastIndex(line, []byte(`{"kind":`))
+	if start <= 0 {
+		return record, false
+	}
+
+	record = outboxRecord{}
+	if err := json.Unmarshal(line[start:], &record); err != nil {
+		return record, false
+	}
+
+	return record, true
+}
+
+// apply applies the log record to the pending entries and dead letters.
+func (o *Outbox) apply(entries []*OutboxEntry, record outboxRecord) []*OutboxEntry {
+	match := func(entry *OutboxEntry) bool { return entry.Seq == record.Seq }
+
+	switch record.Kind {
+	case outboxRecordAdd:
+		if record.Entry != nil {
+			entries = append(entries, record.Entry)
+			o.seq = max(o.seq, record.Entry.Seq)
+		}
+	case outboxRecordDone:
+		entries = slices.DeleteFunc(entries, match)
+		o.dead = slices.DeleteFunc(o.dead, match)
+	case outboxRecordDead:
+		if i := slices.IndexFunc(entries, match); i != -1 {
+			entries[i].LastError = record.Error
+			o.dead = append(o.dead, entries[i])
+			entries = slices.Delete(entries, i, i+1)
+		}
+	}
+
+	return entries
+}
+
+// compact rewrites the log so that it contains only pending entries and dead letters.
+func (o *Outbox) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	o.file = tmp
+	for _, chat := range o.chats {
+		for _, entry := range chat.entries {
+			if err := o.append(outboxRecord{Kind: outboxRecordAdd, Entry: entry}); err != nil {
+				_ = tmp.Close()
+				return err
+			}
+		}
+	}
+	for _, entry := range o.dead {
+		err := o.append(outboxRecord{Kind: outboxRecordAdd, Entry: entry},
+			outboxRecord{Kind: outboxRecordDead, Seq: entry.Seq, Error: entry.LastError})
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return err
+	}
+
+	o.file, err = os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+// sleepUntilNotified waits for the notification, the provided duration or until the context is done.
+// Negative duration means waiting without the timeout.
+func sleepUntilNotified(ctx context.Context, notify <-chan struct{}, wait time.Duration) error {
+	var timeout <-chan time.Time
+	if wait >= 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-notify:
+		return nil
+	case <-timeout:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
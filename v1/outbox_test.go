@@ -0,0 +1,251 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type outboxClient struct {
+	Client
+	mu    sync.Mutex
+	calls []string
+	fail  func(call string) error
+}
+
+func (c *outboxClient) record(call string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fail != nil {
+		if err := c.fail(call); err != nil {
+			return err
+		}
+	}
+
+	c.calls = append(c.calls, call)
+	return nil
+}
+
+func (c *outboxClient) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.calls...)
+}
+
+func (c *outboxClient) MessagesContext(_ context.Context, request SendData) (MessagesResponse, int, error) {
+	return MessagesResponse{}, http.StatusOK, c.record(request.ExternalChatID + ":send:" + request.Message.Text)
+}
+
+func (c *outboxClient) UpdateMessagesContext(
+	_ context.Context, request EditMessageRequest) (MessagesResponse, int, error) {
+	return MessagesResponse{}, http.StatusOK, c.record("edit:" + request.Message.Text)
+}
+
+func (c *outboxClient) DeleteMessageContext(_ context.Context, request DeleteData) (*MessagesResponse, int, error) {
+	return nil, http.StatusOK, c.record("delete:" + request.Message.ExternalID)
+}
+
+func (c *outboxClient) AddMessageReactionContext(_ context.Context, request ReactionRequest) (int, error) {
+	return http.StatusOK, c.record("reaction:" + request.Reaction)
+}
+
+func runOutbox(t *testing.T, outbox *Outbox) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		outbox.Run(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitOutbox(t *testing.T, outbox *Outbox, check func(OutboxStats) bool) {
+	require.Eventually(t, func() bool { return check(outbox.Stats()) }, time.Second, time.Millisecond)
+}
+
+func sendData(chat, text string) SendData {
+	return SendData{Channel: 1, ExternalChatID: chat, Message: Message{Type: MsgTypeText, Text: text}}
+}
+
+func TestOutbox_RestoresAndDeliversInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	client := &outboxClient{}
+
+	outbox, err := NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	_, err = outbox.Messages(sendData("chat", "first"))
+	require.NoError(t, err)
+	_, err = outbox.UpdateMessages("chat", EditMessageRequest{Channel: 1, Message: EditMessageRequestMessage{Text: "edited"}})
+	require.NoError(t, err)
+	_, err = outbox.AddMessageReaction("chat", ReactionRequest{Channel: 1, Reaction: "👍"})
+	require.NoError(t, err)
+	_, err = outbox.DeleteMessage("chat", DeleteData{Channel: 1, Message: Message{ExternalID: "ext"}})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Close())
+
+	outbox, err = NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	defer outbox.Close()
+	assert.Equal(t, OutboxStats{Pending: 4, Chats: 1}, outbox.Stats())
+
+	stop := runOutbox(t, outbox)
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.Delivered == 4 })
+	stop()
+
+	assert.Equal(t, []string{"chat:send:first", "edit:edited", "reaction:👍", "delete:ext"}, client.recorded())
+	assert.Equal(t, OutboxStats{Delivered: 4}, outbox.Stats())
+
+	require.NoError(t, outbox.Close())
+	outbox, err = NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	defer outbox.Close()
+	assert.Equal(t, OutboxStats{}, outbox.Stats())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, data, "log must be compacted")
+}
+
+func TestOutbox_ConcurrentChatsKeepOrder(t *testing.T) {
+	client := &outboxClient{}
+	outbox, err := NewOutbox(client, filepath.Join(t.TempDir(), "outbox.log"), OutboxConfig{Workers: 4})
+	require.NoError(t, err)
+	defer outbox.Close()
+	stop := runOutbox(t, outbox)
+	defer stop()
+
+	for i := 0; i < 10; i++ {
+		for chat := 0; chat < 5; chat++ {
+			_, err := outbox.Messages(sendData(fmt.Sprintf("chat%d", chat), fmt.Sprint(i)))
+			require.NoError(t, err)
+		}
+	}
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.Delivered == 50 })
+
+	next := map[string]int{}
+	for _, call := range client.recorded() {
+		parts := strings.Split(call, ":")
+		assert.Equal(t, fmt.Sprint(next[parts[0]]), parts[2], call)
+		next[parts[0]]++
+	}
+}
+
+func TestOutbox_RetriesAndDeadLetters(t *testing.T) {
+	failures := 0
+	client := &outboxClient{fail: func(call string) error {
+		switch {
+		case strings.HasSuffix(call, "invalid"):
+			return &HTTPClientError{StatusCode: http.StatusBadRequest, ErrorMsg: "Message text is too long"}
+		case strings.HasSuffix(call, "flaky") && failures < 2:
+			failures++
+			return &HTTPClientError{BaseError: errors.New("connection reset")}
+		}
+		return nil
+	}}
+	outbox, err := NewOutbox(client, filepath.Join(t.TempDir(), "outbox.log"), OutboxConfig{
+		Retry: &BackoffRetryPolicy{MaxAttempts: 3, RetryNonIdempotent: true},
+	})
+	require.NoError(t, err)
+	defer outbox.Close()
+	stop := runOutbox(t, outbox)
+	defer stop()
+
+	seq, err := outbox.Messages(sendData("chat", "invalid"))
+	require.NoError(t, err)
+	_, err = outbox.Messages(sendData("chat", "flaky"))
+	require.NoError(t, err)
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.Delivered == 1 })
+
+	stats := outbox.Stats()
+	assert.Equal(t, uint64(2), stats.Retried)
+	assert.Equal(t, uint64(1), stats.Dead)
+	assert.Equal(t, 1, stats.DeadLetters)
+
+	dead := outbox.DeadLetters()
+	require.Len(t, dead, 1)
+	assert.Equal(t, seq, dead[0].Seq)
+	assert.Equal(t, OutboxMessages, dead[0].Op)
+	assert.Equal(t, "Message text is too long", dead[0].LastError)
+
+	client.mu.Lock()
+	client.fail = nil
+	client.mu.Unlock()
+	require.NoError(t, outbox.Requeue(seq))
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.Delivered == 2 })
+	assert.Empty(t, outbox.DeadLetters())
+	assert.ErrorIs(t, outbox.Requeue(seq), ErrOutboxEntryNotFound)
+	assert.ErrorIs(t, outbox.DropDeadLetter(seq), ErrOutboxEntryNotFound)
+}
+
+func TestOutbox_RestoresDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	client := &outboxClient{fail: func(string) error {
+		return &HTTPClientError{StatusCode: http.StatusBadRequest, ErrorMsg: "invalid"}
+	}}
+
+	outbox, err := NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	stop := runOutbox(t, outbox)
+	_, err = outbox.Messages(sendData("chat", "text"))
+	require.NoError(t, err)
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.DeadLetters == 1 })
+	stop()
+	require.NoError(t, outbox.Close())
+
+	outbox, err = NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	dead := outbox.DeadLetters()
+	require.Len(t, dead, 1)
+	assert.Equal(t, "invalid", dead[0].LastError)
+	require.NoError(t, outbox.DropDeadLetter(dead[0].Seq))
+	assert.Equal(t, OutboxStats{}, outbox.Stats())
+}
+
+func TestOutbox_RestoresAfterCorruptRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	client := &outboxClient{}
+
+	outbox, err := NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	for _, text := range []string{"first", "second", "third"} {
+		_, err = outbox.Messages(sendData("chat", text))
+		require.NoError(t, err)
+	}
+	require.NoError(t, outbox.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+	require.Len(t, lines, 4)
+
+	// The write of the record has failed halfway, a corrupt line is in the middle and the last write is incomplete.
+	corrupted := lines[0] + `{"kind":"add","entry":{"seq":` + lines[1] + "{broken\n" + lines[2] + `{"kind":"do`
+	require.NoError(t, os.WriteFile(path, []byte(corrupted), 0o600))
+
+	outbox, err = NewOutbox(client, path, OutboxConfig{})
+	require.NoError(t, err)
+	defer outbox.Close()
+	assert.Equal(t, OutboxStats{Pending: 3, Chats: 1, Corrupted: 1}, outbox.Stats())
+
+	stop := runOutbox(t, outbox)
+	waitOutbox(t, outbox, func(stats OutboxStats) bool { return stats.Delivered == 3 })
+	stop()
+	assert.Equal(t, []string{"chat:send:first", "chat:send:second", "chat:send:third"}, client.recorded())
+}
@@ -0,0 +1,164 @@
+// Package prommetrics implements v1.Metrics which exposes the client metrics in the Prometheus text
+// exposition format. It doesn't depend on the Prometheus client library, so the metrics can be served
+// by the application itself or written to a file for the node exporter textfile collector.
+//
+// Example:
+//
+//	metrics := prommetrics.New()
+//	client := v1.New("https://message-gateway.url", "token").WithMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+//
+// The following metrics are exposed:
+//
+//	mg_transport_client_requests_total{method,route,status}
+//	mg_transport_client_request_duration_seconds{method,route}
+//	mg_transport_client_retries_total{method,route}
+//	mg_transport_client_rate_limited_total{method,route}
+//	mg_transport_client_limiter_wait_seconds{method,route}
+//	mg_transport_client_response_size_bytes{method,route}
+package prommetrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const defaultNamespace = "mg_transport_client"
+
+// DefaultDurationBuckets are the buckets of the request duration and the limiter wait histograms, in seconds.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// DefaultSizeBuckets are the buckets of the response size histogram, in bytes.
+var DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+
+// Option configures the Metrics.
+type Option func(*Metrics)
+
+// WithNamespace sets the prefix of the metric names. Default is "mg_transport_client".
+func WithNamespace(namespace string) Option {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// WithDurationBuckets sets the buckets of the request duration and the limiter wait histograms, in seconds.
+func WithDurationBuckets(buckets ...float64) Option {
+	return func(m *Metrics) {
+		m.durationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets sets the buckets of the response size histogram, in bytes.
+func WithSizeBuckets(buckets ...float64) Option {
+	return func(m *Metrics) {
+		m.sizeBuckets = buckets
+	}
+}
+
+// Metrics collects the client metrics. It implements v1.Metrics and http.Handler.
+type Metrics struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu           sync.Mutex
+	requests     *counterVec
+	retries      *counterVec
+	rateLimited  *counterVec
+	durations    *histogramVec
+	limiterWaits *histogramVec
+	sizes        *histogramVec
+}
+
+var _ v1.Metrics = (*Metrics)(nil)
+
+// New creates the Metrics.
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		namespace:       defaultNamespace,
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.requests = newCounterVec(m.name("requests_total"), "Number of the finished API calls.")
+	m.retries = newCounterVec(m.name("retries_total"), "Number of the retried API call attempts.")
+	m.rateLimited = newCounterVec(m.name("rate_limited_total"), "Number of the API call attempts rejected with 429.")
+	m.durations = newHistogramVec(m.name("request_duration_seconds"),
+		"Duration of the API calls including retries and rate limiter waits.", m.durationBuckets)
+	m.limiterWaits = newHistogramVec(m.name("limiter_wait_seconds"),
+		"Time spent waiting for the rate limiter before the API call attempts.", m.durationBuckets)
+	m.sizes = newHistogramVec(m.name("response_size_bytes"), "Size of the API responses.", m.sizeBuckets)
+	return m
+}
+
+// RequestDone implements v1.Metrics.
+func (m *Metrics) RequestDone(request v1.RequestMetrics) {
+	labels := []label{{"method", request.Method}, {"route", request.Route}}
+	status := "error"
+	if request.StatusCode != 0 {
+		status = strconv.Itoa(request.StatusCode)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests.add(append(labels, label{"status", status}), 1)
+	m.retries.add(labels, float64(max(request.Attempts-1, 0)))
+	m.rateLimited.add(labels, float64(request.RateLimited))
+	m.durations.observe(labels, request.Duration.Seconds())
+	if request.StatusCode != 0 {
+		m.sizes.observe(labels, float64(request.ResponseSize))
+	}
+}
+
+// LimiterWait implements v1.Metrics.
+func (m *Metrics) LimiterWait(method, route string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limiterWaits.observe([]label{{"method", method}, {"route", route}}, wait.Seconds())
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	m.mu.Lock()
+	m.requests.write(cw)
+	m.retries.write(cw)
+	m.rateLimited.write(cw)
+	m.durations.write(cw)
+	m.limiterWaits.write(cw)
+	m.sizes.write(cw)
+	m.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = m.WriteTo(w)
+}
+
+func (m *Metrics) name(name string) string {
+	if m.namespace == "" {
+		return name
+	}
+	return m.namespace + "_" + name
+}
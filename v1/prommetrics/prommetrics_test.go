@@ -0,0 +1,101 @@
+package prommetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/retailcrm/mg-transport-api-client-go/v1"
+)
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := New(WithNamespace("mg"), WithDurationBuckets(1, 0.1), WithSizeBuckets(100))
+	m.RequestDone(v1.RequestMetrics{
+		Method:       http.MethodPost,
+		Route:        "/messages",
+		StatusCode:   http.StatusOK,
+		Duration:     50 * time.Millisecond,
+		Attempts:     3,
+		RateLimited:  2,
+		ResponseSize: 150,
+	})
+	m.RequestDone(v1.RequestMetrics{Method: http.MethodGet, Route: "/channels", Duration: 2 * time.Second, Attempts: 1})
+	m.LimiterWait(http.MethodPost, "/messages", 500*time.Millisecond)
+
+	var out strings.Builder
+	n, err := m.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+	assert.Equal(t, `# HELP mg_requests_total Number of the finished API calls.
+# TYPE mg_requests_total counter
+mg_requests_total{method="GET",route="/channels",status="error"} 1
+mg_requests_total{method="POST",route="/messages",status="200"} 1
+# HELP mg_retries_total Number of the retried API call attempts.
+# TYPE mg_retries_total counter
+mg_retries_total{method="GET",route="/channels"} 0
+mg_retries_total{method="POST",route="/messages"} 2
+# HELP mg_rate_limited_total Number of the API call attempts rejected with 429.
+# TYPE mg_rate_limited_total counter
+mg_rate_limited_total{method="GET",route="/channels"} 0
+mg_rate_limited_total{method="POST",route="/messages"} 2
+# HELP mg_request_duration_seconds Duration of the API calls including retries and rate limiter waits.
+# TYPE mg_request_duration_seconds histogram
+mg_request_duration_seconds_bucket{method="GET",route="/channels",le="0.1"} 0
+mg_request_duration_seconds_bucket{method="GET",route="/channels",le="1"} 0
+mg_request_duration_seconds_bucket{method="GET",route="/channels",le="+Inf"} 1
+mg_request_duration_seconds_sum{method="GET",route="/channels"} 2
+mg_request_duration_seconds_count{method="GET",route="/channels"} 1
+mg_request_duration_seconds_bucket{method="POST",route="/messages",le="0.1"} 1
+mg_request_duration_seconds_bucket{method="POST",route="/messages",le="1"} 1
+mg_request_duration_seconds_bucket{method="POST",route="/messages",le="+Inf"} 1
+mg_request_duration_seconds_sum{method="POST",route="/messages"} 0.05
+mg_request_duration_seconds_count{method="POST",route="/messages"} 1
+# HELP mg_limiter_wait_seconds Time spent waiting for the rate limiter before the API call attempts.
+# TYPE mg_limiter_wait_seconds histogram
+mg_limiter_wait_seconds_bucket{method="POST",route="/messages",le="0.1"} 0
+mg_limiter_wait_seconds_bucket{method="POST",route="/messages",le="1"} 1
+mg_limiter_wait_seconds_bucket{method="POST",route="/messages",le="+Inf"} 1
+mg_limiter_wait_seconds_sum{method="POST",route="/messages"} 0.5
+mg_limiter_wait_seconds_count{method="POST",route="/messages"} 1
+# HELP mg_response_size_bytes Size of the API responses.
+# TYPE mg_response_size_bytes histogram
+mg_response_size_bytes_bucket{method="POST",route="/messages",le="100"} 0
+mg_response_size_bytes_bucket{method="POST",route="/messages",le="+Inf"} 1
+mg_response_size_bytes_sum{method="POST",route="/messages"} 150
+mg_response_size_bytes_count{method="POST",route="/messages"} 1
+`, out.String())
+}
+
+func TestMetrics_EscapesLabels(t *testing.T) {
+	m := New()
+	m.LimiterWait("GET", "/a\"b\\c\nd", 0)
+
+	var out strings.Builder
+	_, err := m.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `route="/a\"b\\c\nd"`)
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message_id":1}`))
+	}))
+	defer srv.Close()
+
+	m := New()
+	_, _, err := v1.New(srv.URL, "token").WithMetrics(m).Messages(v1.SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(),
+		`mg_transport_client_requests_total{method="POST",route="/messages",status="200"} 1`)
+	assert.Contains(t, rec.Body.String(),
+		`mg_transport_client_response_size_bytes_sum{method="POST",route="/messages"} 16`)
+}
@@ -0,0 +1,185 @@
+package prommetrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+// labelsKey returns the series key of the labels. The labels are always passed in the same order.
+func labelsKey(labels []label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// formatLabels returns the labels in the exposition format, e.g. {method="GET",route="/channels"}.
+func formatLabels(labels []label, extra ...label) string {
+	all := append(append([]label{}, labels...), extra...)
+	if len(all) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range all {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(l.value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+type counterVec struct {
+	name   string
+	help   string
+	series map[string]*counter
+}
+
+type counter struct {
+	labels []label
+	value  float64
+}
+
+func newCounterVec(name, help string) *counterVec {
+	return &counterVec{name: name, help: help, series: make(map[string]*counter)}
+}
+
+func (v *counterVec) add(labels []label, value float64) {
+	key := labelsKey(labels)
+	c, ok := v.series[key]
+	if !ok {
+		c = &counter{labels: labels}
+		v.series[key] = c
+	}
+	c.value += value
+}
+
+func (v *counterVec) write(w *countingWriter) {
+	if len(v.series) == 0 {
+		return
+	}
+
+	w.printf("# HELP %s %s\n# TYPE %s counter\n", v.name, helpReplacer.Replace(v.help), v.name)
+	for _, key := range sortedKeys(v.series) {
+		c := v.series[key]
+		w.printf("%s%s %s\n", v.name, formatLabels(c.labels), formatFloat(c.value))
+	}
+}
+
+type histogramVec struct {
+	name    string
+	help    string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	labels []label
+	counts []uint64 // non-cumulative counts for every bucket
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64) *histogramVec {
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &histogramVec{name: name, help: help, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (v *histogramVec) observe(labels []label, value float64) {
+	key := labelsKey(labels)
+	h, ok := v.series[key]
+	if !ok {
+		h = &histogram{labels: labels, counts: make([]uint64, len(v.buckets))}
+		v.series[key] = h
+	}
+
+	if i := sort.SearchFloat64s(v.buckets, value); i < len(v.buckets) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+}
+
+func (v *histogramVec) write(w *countingWriter) {
+	if len(v.series) == 0 {
+		return
+	}
+
+	w.printf("# HELP %s %s\n# TYPE %s histogram\n", v.name, helpReplacer.Replace(v.help), v.name)
+	for _, key := range sortedKeys(v.series) {
+		h := v.series[key]
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += h.counts[i]
+			w.printf("%s_bucket%s %d\n", v.name, formatLabels(h.labels, label{"le", formatFloat(bound)}), cumulative)
+		}
+		w.printf("%s_bucket%s %d\n", v.name, formatLabels(h.labels, label{"le", "+Inf"}), h.count)
+		w.printf("%s_sum%s %s\n", v.name, formatLabels(h.labels), formatFloat(h.sum))
+		w.printf("%s_count%s %d\n", v.name, formatLabels(h.labels), h.count)
+	}
+}
+
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countingWriter counts the written bytes and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"context"
 	"hash/fnv"
 	"runtime"
 	"sync"
@@ -23,6 +24,38 @@ type Limiter interface {
 	Obtain(id string)
 }
 
+// ContextLimiter is the Limiter which can be waited on with a context and queried without blocking.
+// MgClient uses it instead of Obtain when the limiter implements it.
+type ContextLimiter interface {
+	Limiter
+	// Wait blocks until the request with the provided id is allowed or the context is done.
+	// The slot is returned to the limiter if the context is done before the request is allowed.
+	Wait(ctx context.Context, id string) error
+	// Reserve takes the next available slot and returns the reservation which tells how long
+	// the caller must wait before sending the request.
+	Reserve(id string) Reservation
+	// Allow takes the slot only if the request can be sent right now.
+	Allow(id string) bool
+}
+
+// Reservation is a slot taken from the ContextLimiter.
+type Reservation struct {
+	delay  time.Duration
+	cancel func()
+}
+
+// Delay returns the time to wait before the reserved slot can be used.
+func (r Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel returns the reserved slot to the limiter. It should be called if the request will not be sent.
+func (r Reservation) Cancel() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
 // TokensBucket implements a sharded rate limiter with fixed window and tokens.
 type TokensBucket struct {
 	maxRPS          uint32
@@ -30,17 +63,36 @@ type TokensBucket struct {
 	checkTokenTime  time.Duration
 	shards          []*tokenShard
 	shardCount      uint32
-	cancel          atomic.Bool
+	lazyCleanup     bool
+	done            chan struct{}
+	closeOnce       sync.Once
 	sleep           sleeper
+	now             func() time.Time
+	onThrottle      ThrottleFunc
+	stats           waitStats
 }
 
+// TokensBucketOption configures the TokensBucket.
+type TokensBucketOption func(*TokensBucket)
+
 type tokenShard struct {
-	tokens map[string]*token
-	mu     sync.Mutex
+	tokens      map[string]*token
+	nextCleanup int64 // used only with the lazy cleanup, in Unix nanoseconds
+	mu          sync.Mutex
+}
+
+// WithLazyCleanup disables the background cleanup goroutine. The unused tokens are removed from the shard
+// when it's accessed and at least checkTokenTime has passed since its previous cleanup.
+func WithLazyCleanup() TokensBucketOption {
+	return func(bucket *TokensBucket) {
+		bucket.lazyCleanup = true
+	}
 }
 
-// NewTokensBucket creates a sharded token bucket limiter.
-func NewTokensBucket(maxRPS uint32, unusedTokenTime, checkTokenTime time.Duration) Limiter {
+// NewTokensBucket creates a sharded token bucket limiter. The bucket removes the unused tokens
+// in the background goroutine which is stopped by Close, unless WithLazyCleanup option is provided.
+func NewTokensBucket(
+	maxRPS uint32, unusedTokenTime, checkTokenTime time.Duration, opts ...TokensBucketOption) *TokensBucket {
 	shardCount := uint32(runtime.NumCPU() * shardsPerCoreMultiplier)
 	shards := make([]*tokenShard, shardCount)
 	for i := range shards {
@@ -55,81 +107,189 @@ func NewTokensBucket(maxRPS uint32, unusedTokenTime, checkTokenTime time.Duratio
 		shardCount:      shardCount,
 		sleep:           realSleeper{},
 	}
+	for _, opt := range opts {
+		opt(bucket)
+	}
 
-	go bucket.cleanupRoutine()
-	runtime.SetFinalizer(bucket, destructBucket)
+	if !bucket.lazyCleanup {
+		bucket.done = make(chan struct{})
+		go bucket.cleanupRoutine()
+	}
 	return bucket
 }
 
 // Obtain request hit. Will throttle RPS.
 func (m *TokensBucket) Obtain(id string) {
+	if delay := m.Reserve(id).Delay(); delay > 0 {
+		m.throttled(id, delay)
+		m.sleep.Sleep(delay)
+	}
+}
+
+// Wait blocks until the request is allowed or the context is done.
+func (m *TokensBucket) Wait(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := m.Reserve(id)
+	if r.Delay() <= 0 {
+		return nil
+	}
+
+	m.throttled(id, r.Delay())
+	if err := sleepContext(ctx, r.Delay()); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// Reserve takes the slot in the current window or, if it is exhausted, in the next one.
+func (m *TokensBucket) Reserve(id string) Reservation {
+	delay, window, _ := m.take(id, true)
+	return Reservation{
+		delay:  delay,
+		cancel: func() { m.release(id, window) },
+	}
+}
+
+// Allow takes the slot only if the current window is not exhausted.
+func (m *TokensBucket) Allow(id string) bool {
+	_, _, ok := m.take(id, false)
+	return ok
+}
+
+// take reserves the slot for the id and returns the time to wait for it along with the start of the window
+// the slot belongs to. The shard lock is never held while waiting, so other ids in the shard are not blocked.
+func (m *TokensBucket) take(id string, reserve bool) (time.Duration, int64, bool) {
 	shard := m.getShard(id)
 
 	shard.mu.Lock()
 	defer shard.mu.Unlock()
 
-	item, exists := shard.tokens[id]
-	now := time.Now().UnixNano()
+	now := m.nowNano()
+	if m.lazyCleanup && now >= shard.nextCleanup {
+		m.cleanupShard(shard, now)
+		shard.nextCleanup = now + int64(m.checkTokenTime)
+	}
 
+	item, exists := shard.tokens[id]
 	if !exists {
 		shard.tokens[id] = &token{
 			rps:     1,
 			lastUse: now,
 		}
-		return
+		return 0, now, true
 	}
 
-	sleepTime := int64(time.Second) - (now - item.lastUse)
-	if sleepTime <= 0 {
+	switch {
+	case now-item.lastUse >= int64(time.Second):
 		item.lastUse = now
 		atomic.StoreUint32(&item.rps, 1)
-	} else if atomic.LoadUint32(&item.rps) >= m.maxRPS {
-		m.sleep.Sleep(time.Duration(sleepTime))
-		item.lastUse = time.Now().UnixNano()
-		atomic.StoreUint32(&item.rps, 1)
-	} else {
+	case atomic.LoadUint32(&item.rps) < m.maxRPS && (reserve || item.lastUse <= now):
 		atomic.AddUint32(&item.rps, 1)
+	case !reserve:
+		return 0, 0, false
+	default:
+		item.lastUse += int64(time.Second)
+		atomic.StoreUint32(&item.rps, 1)
 	}
+
+	return time.Duration(max(item.lastUse-now, 0)), item.lastUse, true
+}
+
+// release returns the slot reserved in the provided window if that window is still the current one.
+func (m *TokensBucket) release(id string, window int64) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, exists := shard.tokens[id]
+	if exists && item.lastUse == window && atomic.LoadUint32(&item.rps) > 0 {
+		atomic.AddUint32(&item.rps, ^uint32(0))
+	}
+}
+
+func (m *TokensBucket) nowNano() int64 {
+	if m.now != nil {
+		return m.now().UnixNano()
+	}
+	return time.Now().UnixNano()
 }
 
 func (m *TokensBucket) getShard(id string) *tokenShard {
-	hash := fnv.New32a()
-	_, _ = hash.Write([]byte(id))
-	return m.shards[hash.Sum32()%m.shardCount]
+	return m.shards[shardIndex(id, m.shardCount)]
+}
+
+// Close stops the background cleanup goroutine. The bucket still limits the requests after Close
+// but the unused tokens are not removed anymore. Close is safe to call more than once.
+func (m *TokensBucket) Close() error {
+	m.closeOnce.Do(func() {
+		if m.done != nil {
+			close(m.done)
+		}
+	})
+	return nil
 }
 
 func (m *TokensBucket) cleanupRoutine() {
-	ticker := time.NewTicker(m.checkTokenTime)
+	runCleanup(m.checkTokenTime, m.done, func(now int64) {
+		for _, shard := range m.shards {
+			shard.mu.Lock()
+			m.cleanupShard(shard, now)
+			shard.mu.Unlock()
+		}
+	})
+}
+
+// cleanupShard removes the unused tokens from the shard. The shard must be locked.
+func (m *TokensBucket) cleanupShard(shard *tokenShard, now int64) {
+	for id, token := range shard.tokens {
+		if now-token.lastUse >= m.unusedTokenTime {
+			delete(shard.tokens, id)
+		}
+	}
+}
+
+// runCleanup calls the clean function every interval until the done channel is closed.
+func runCleanup(interval time.Duration, done <-chan struct{}, clean func(now int64)) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if m.cancel.Load() {
-				return
-			}
-			now := time.Now().UnixNano()
-			for _, shard := range m.shards {
-				shard.mu.Lock()
-				for id, token := range shard.tokens {
-					if now-token.lastUse >= m.unusedTokenTime {
-						delete(shard.tokens, id)
-					}
-				}
-				shard.mu.Unlock()
-			}
+			clean(time.Now().UnixNano())
+		case <-done:
+			return
 		}
 	}
 }
 
-func destructBucket(m *TokensBucket) {
-	m.cancel.Store(true)
+func shardIndex(id string, shardCount uint32) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return hash.Sum32() % shardCount
 }
 
 type noopLimiter struct{}
 
 func (l *noopLimiter) Obtain(string) {}
 
+func (l *noopLimiter) Wait(ctx context.Context, _ string) error {
+	return ctx.Err()
+}
+
+func (l *noopLimiter) Reserve(string) Reservation {
+	return Reservation{}
+}
+
+func (l *noopLimiter) Allow(string) bool {
+	return true
+}
+
 type sleeper interface {
 	Sleep(time.Duration)
 }
@@ -0,0 +1,360 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdaptiveMinRPS           = 1
+	defaultAdaptiveIncrease         = 1
+	defaultAdaptiveDecrease         = 0.5
+	defaultAdaptiveRecoveryInterval = time.Second
+	defaultAdaptiveUnusedTokenTime  = time.Hour
+	defaultAdaptiveCheckTokenTime   = time.Minute
+	// unixResetThreshold separates rate-limit reset values which are Unix timestamps from delays in seconds.
+	unixResetThreshold = 1e9
+)
+
+// ResponseObserver is implemented by limiters which adjust their rate using the API responses.
+// MgClient calls ObserveResponse for every received response if its limiter implements this interface.
+type ResponseObserver interface {
+	ObserveResponse(id string, statusCode int, header http.Header)
+}
+
+// AdaptiveLimiterConfig contains AdaptiveLimiter settings. Zero values are replaced with defaults.
+type AdaptiveLimiterConfig struct {
+	// MaxRPS is the initial and the highest rate per id. MaxRPS constant is used by default.
+	MaxRPS float64
+	// MinRPS is the lowest rate the limiter can decrease to. Default is 1.
+	MinRPS float64
+	// Increase is added to the rate every RecoveryInterval after the last decrease. Default is 1.
+	Increase float64
+	// Decrease is the factor the rate is multiplied by on 429 response. Default is 0.5.
+	Decrease float64
+	// RecoveryInterval is the period of the additive increase. It is also the minimal time between two decreases
+	// so the burst of 429 responses to the concurrent requests is counted only once. Default is one second.
+	RecoveryInterval time.Duration
+	// UnusedTokenTime is the time after which the unused id is forgotten. Default is one hour.
+	UnusedTokenTime time.Duration
+	// CheckTokenTime is the period of the unused ids cleanup. Default is one minute.
+	CheckTokenTime time.Duration
+	// LazyCleanup disables the background cleanup goroutine. The unused ids are removed from the shard
+	// when it's accessed and at least CheckTokenTime has passed since its previous cleanup.
+	LazyCleanup bool
+}
+
+// AdaptiveLimiter is the rate limiter which learns the allowed rate from the server responses.
+//
+// The requests for every id are spaced evenly according to the current rate. The rate is lowered
+// multiplicatively on every 429 response and recovers additively over time (AIMD), never exceeding MaxRPS
+// or the limit reported by the server. Retry-After and exhausted rate-limit headers block the id until
+// the server allows requests again. The X-RateLimit-* and RateLimit-* headers are supported; the limit
+// is treated as requests per second.
+type AdaptiveLimiter struct {
+	config     AdaptiveLimiterConfig
+	shards     []*adaptiveShard
+	shardCount uint32
+	done       chan struct{}
+	closeOnce  sync.Once
+	sleep      sleeper
+	now        func() time.Time
+}
+
+type adaptiveShard struct {
+	tokens      map[string]*adaptiveToken
+	nextCleanup int64 // used only with the lazy cleanup, in Unix nanoseconds
+	mu          sync.Mutex
+}
+
+type adaptiveToken struct {
+	tat          int64   // theoretical arrival time of the next request, in Unix nanoseconds
+	rate         float64 // rate set by the last decrease
+	ceiling      float64 // rate limit reported by the server, zero if unknown
+	decreasedAt  int64   // time of the last decrease, in Unix nanoseconds
+	blockedUntil int64   // requests are not allowed until this time, in Unix nanoseconds
+}
+
+// NewAdaptiveLimiter creates a sharded adaptive limiter. The unused ids are removed in the background goroutine
+// which is stopped by Close, unless LazyCleanup is set.
+func NewAdaptiveLimiter(config AdaptiveLimiterConfig) *AdaptiveLimiter {
+	config = config.withDefaults()
+	shardCount := uint32(runtime.NumCPU() * shardsPerCoreMultiplier)
+	shards := make([]*adaptiveShard, shardCount)
+	for i := range shards {
+		shards[i] = &adaptiveShard{tokens: make(map[string]*adaptiveToken)}
+	}
+
+	limiter := &AdaptiveLimiter{
+		config:     config,
+		shards:     shards,
+		shardCount: shardCount,
+		sleep:      realSleeper{},
+	}
+
+	if !config.LazyCleanup {
+		limiter.done = make(chan struct{})
+		go limiter.cleanupRoutine()
+	}
+	return limiter
+}
+
+func (c AdaptiveLimiterConfig) withDefaults() AdaptiveLimiterConfig {
+	if c.MaxRPS <= 0 {
+		c.MaxRPS = MaxRPS
+	}
+	if c.MinRPS <= 0 {
+		c.MinRPS = defaultAdaptiveMinRPS
+	}
+	c.MinRPS = min(c.MinRPS, c.MaxRPS)
+	if c.Increase <= 0 {
+		c.Increase = defaultAdaptiveIncrease
+	}
+	if c.Decrease <= 0 || c.Decrease >= 1 {
+		c.Decrease = defaultAdaptiveDecrease
+	}
+	if c.RecoveryInterval <= 0 {
+		c.RecoveryInterval = defaultAdaptiveRecoveryInterval
+	}
+	if c.UnusedTokenTime <= 0 {
+		c.UnusedTokenTime = defaultAdaptiveUnusedTokenTime
+	}
+	if c.CheckTokenTime <= 0 {
+		c.CheckTokenTime = defaultAdaptiveCheckTokenTime
+	}
+	return c
+}
+
+// Obtain request hit. Will throttle RPS.
+func (m *AdaptiveLimiter) Obtain(id string) {
+	if delay := m.Reserve(id).Delay(); delay > 0 {
+		m.sleep.Sleep(delay)
+	}
+}
+
+// Wait blocks until the request is allowed or the context is done.
+func (m *AdaptiveLimiter) Wait(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := m.Reserve(id)
+	if r.Delay() <= 0 {
+		return nil
+	}
+
+	if err := sleepContext(ctx, r.Delay()); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// Reserve takes the next slot for the id according to its current rate.
+func (m *AdaptiveLimiter) Reserve(id string) Reservation {
+	delay, start, tat, _ := m.take(id, true)
+	return Reservation{
+		delay:  delay,
+		cancel: func() { m.release(id, start, tat) },
+	}
+}
+
+// Allow takes the slot only if the request can be sent right now.
+func (m *AdaptiveLimiter) Allow(id string) bool {
+	_, _, _, ok := m.take(id, false)
+	return ok
+}
+
+// Rate returns the current rate of the id in requests per second.
+func (m *AdaptiveLimiter) Rate(id string) float64 {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, exists := shard.tokens[id]
+	if !exists {
+		return m.config.MaxRPS
+	}
+	return m.currentRate(item, m.nowNano())
+}
+
+// Rates returns the current rates of all tracked ids keyed by HashLimiterID of the id,
+// so the result can be logged or exported without leaking the transport tokens.
+func (m *AdaptiveLimiter) Rates() map[string]float64 {
+	now := m.nowNano()
+	rates := make(map[string]float64)
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for id, item := range shard.tokens {
+			rates[HashLimiterID(id)] = m.currentRate(item, now)
+		}
+		shard.mu.Unlock()
+	}
+	return rates
+}
+
+// ObserveResponse implements ResponseObserver.
+func (m *AdaptiveLimiter) ObserveResponse(id string, statusCode int, header http.Header) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := m.nowNano()
+	item := m.token(shard, id, now)
+
+	if delay, ok := RetryAfter(header); ok {
+		item.blockedUntil = max(item.blockedUntil, now+int64(delay))
+	}
+
+	limit, remaining, reset := parseRateLimitHeaders(header, now)
+	if limit > 0 {
+		item.ceiling = limit
+	}
+	if remaining == 0 && reset > now {
+		item.blockedUntil = max(item.blockedUntil, reset)
+	}
+
+	if statusCode == http.StatusTooManyRequests &&
+		(item.decreasedAt == 0 || now-item.decreasedAt >= int64(m.config.RecoveryInterval)) {
+		item.rate = max(m.currentRate(item, now)*m.config.Decrease, m.config.MinRPS)
+		item.decreasedAt = now
+	}
+}
+
+func (m *AdaptiveLimiter) take(id string, reserve bool) (time.Duration, int64, int64, bool) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := m.nowNano()
+	item := m.token(shard, id, now)
+	start := max(item.tat, item.blockedUntil, now)
+	if start > now && !reserve {
+		return 0, 0, 0, false
+	}
+
+	item.tat = start + int64(float64(time.Second)/m.currentRate(item, now))
+	return time.Duration(start - now), start, item.tat, true
+}
+
+// release returns the slot if no other slot was taken for the id after it.
+func (m *AdaptiveLimiter) release(id string, start, tat int64) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if item, exists := shard.tokens[id]; exists && item.tat == tat {
+		item.tat = start
+	}
+}
+
+// token returns the state of the id creating it if necessary. The shard must be locked.
+func (m *AdaptiveLimiter) token(shard *adaptiveShard, id string, now int64) *adaptiveToken {
+	if m.config.LazyCleanup && now >= shard.nextCleanup {
+		m.cleanupShard(shard, now)
+		shard.nextCleanup = now + int64(m.config.CheckTokenTime)
+	}
+
+	item, exists := shard.tokens[id]
+	if !exists {
+		item = &adaptiveToken{tat: now, rate: m.config.MaxRPS}
+		shard.tokens[id] = item
+	}
+	return item
+}
+
+// currentRate returns the rate of the token with the additive increase applied since the last decrease.
+func (m *AdaptiveLimiter) currentRate(item *adaptiveToken, now int64) float64 {
+	ceiling := m.config.MaxRPS
+	if item.ceiling > 0 {
+		ceiling = min(ceiling, item.ceiling)
+	}
+
+	rate := item.rate
+	if item.decreasedAt != 0 {
+		rate += m.config.Increase * float64(now-item.decreasedAt) / float64(m.config.RecoveryInterval)
+	}
+	return max(min(rate, ceiling), m.config.MinRPS)
+}
+
+func (m *AdaptiveLimiter) nowNano() int64 {
+	if m.now != nil {
+		return m.now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+func (m *AdaptiveLimiter) getShard(id string) *adaptiveShard {
+	return m.shards[shardIndex(id, m.shardCount)]
+}
+
+func (m *AdaptiveLimiter) cleanupRoutine() {
+	runCleanup(m.config.CheckTokenTime, m.done, func(now int64) {
+		for _, shard := range m.shards {
+			shard.mu.Lock()
+			m.cleanupShard(shard, now)
+			shard.mu.Unlock()
+		}
+	})
+}
+
+// cleanupShard removes the unused ids from the shard. The shard must be locked.
+func (m *AdaptiveLimiter) cleanupShard(shard *adaptiveShard, now int64) {
+	unused := m.config.UnusedTokenTime.Nanoseconds()
+	for id, item := range shard.tokens {
+		if now-max(item.tat, item.blockedUntil) >= unused {
+			delete(shard.tokens, id)
+		}
+	}
+}
+
+// Close stops the background cleanup goroutine. Close is safe to call more than once.
+func (m *AdaptiveLimiter) Close() error {
+	m.closeOnce.Do(func() {
+		if m.done != nil {
+			close(m.done)
+		}
+	})
+	return nil
+}
+
+// parseRateLimitHeaders returns the limit, the remaining requests count and the reset time in Unix nanoseconds
+// from the X-RateLimit-* or RateLimit-* headers. Missing limit and reset are returned as zero, missing remaining
+// count is returned as -1.
+func parseRateLimitHeaders(header http.Header, now int64) (float64, int64, int64) {
+	get := func(name string) string {
+		if value := header.Get("X-RateLimit-" + name); value != "" {
+			return value
+		}
+		return header.Get("RateLimit-" + name)
+	}
+
+	limit, err := strconv.ParseFloat(get("Limit"), 64)
+	if err != nil || limit < 0 {
+		limit = 0
+	}
+
+	remaining, err := strconv.ParseInt(get("Remaining"), 10, 64)
+	if err != nil {
+		remaining = -1
+	}
+
+	var reset int64
+	if value, err := strconv.ParseInt(get("Reset"), 10, 64); err == nil && value > 0 {
+		if value > unixResetThreshold {
+			reset = time.Unix(value, 0).UnixNano()
+		} else {
+			reset = now + value*int64(time.Second)
+		}
+	}
+
+	return limit, remaining, reset
+}
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AdaptiveLimiterTest struct {
+	suite.Suite
+	clock *fakeSleeper
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	suite.Run(t, new(AdaptiveLimiterTest))
+}
+
+func (t *AdaptiveLimiterTest) new(config AdaptiveLimiterConfig) *AdaptiveLimiter {
+	t.clock = &fakeSleeper{start: time.Now()}
+	return &AdaptiveLimiter{
+		config:     config.withDefaults(),
+		shards:     []*adaptiveShard{{tokens: make(map[string]*adaptiveToken)}},
+		shardCount: 1,
+		sleep:      t.clock,
+		now:        t.clock.Now,
+	}
+}
+
+func (t *AdaptiveLimiterTest) Test_NewAdaptiveLimiter() {
+	limiter := NewAdaptiveLimiter(AdaptiveLimiterConfig{})
+	defer limiter.Close()
+	t.Require().NotNil(limiter)
+	t.Assert().Implements((*ContextLimiter)(nil), limiter)
+	t.Assert().Equal(float64(MaxRPS), limiter.Rate("a"))
+}
+
+func (t *AdaptiveLimiterTest) Test_Obtain_Spacing() {
+	limiter := t.new(AdaptiveLimiterConfig{MaxRPS: 10})
+
+	for i := 0; i < 11; i++ {
+		limiter.Obtain("a")
+	}
+	t.Assert().Equal(10, int(t.clock.total.Load()))
+	t.Assert().Equal(time.Second, time.Duration(t.clock.elapsed.Load()))
+}
+
+func (t *AdaptiveLimiterTest) Test_DecreaseAndRecover() {
+	limiter := t.new(AdaptiveLimiterConfig{MaxRPS: 100, MinRPS: 12, Increase: 5})
+
+	limiter.ObserveResponse("a", http.StatusOK, nil)
+	t.Assert().Equal(100.0, limiter.Rate("a"))
+
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, nil)
+	t.Assert().Equal(50.0, limiter.Rate("a"))
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, nil)
+	t.Assert().Equal(50.0, limiter.Rate("a"), "concurrent 429 responses should decrease the rate once")
+	t.Assert().Equal(100.0, limiter.Rate("b"))
+
+	t.clock.Sleep(time.Second)
+	t.Assert().Equal(55.0, limiter.Rate("a"))
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, nil)
+	t.Assert().Equal(27.5, limiter.Rate("a"))
+	t.clock.Sleep(time.Second)
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, nil)
+	t.Assert().Equal(16.25, limiter.Rate("a"))
+	t.clock.Sleep(time.Second)
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, nil)
+	t.Assert().Equal(12.0, limiter.Rate("a"), "rate should not fall below MinRPS")
+
+	t.clock.Sleep(time.Minute)
+	t.Assert().Equal(100.0, limiter.Rate("a"), "rate should not exceed MaxRPS")
+	t.Assert().Equal(map[string]float64{HashLimiterID("a"): 100}, limiter.Rates())
+}
+
+func (t *AdaptiveLimiterTest) Test_RatesHidesIDs() {
+	limiter := t.new(AdaptiveLimiterConfig{})
+	limiter.ObserveResponse("token", http.StatusTooManyRequests, nil)
+
+	rates := limiter.Rates()
+	t.Assert().NotContains(rates, "token")
+	t.Assert().Equal(50.0, rates[HashLimiterID("token")])
+}
+
+func (t *AdaptiveLimiterTest) Test_RetryAfter() {
+	limiter := t.new(AdaptiveLimiterConfig{})
+	header := http.Header{}
+	header.Set("Retry-After", "2")
+
+	limiter.ObserveResponse("a", http.StatusTooManyRequests, header)
+	t.Assert().False(limiter.Allow("a"))
+	t.Assert().True(limiter.Allow("b"))
+	t.Assert().Equal(2*time.Second, limiter.Reserve("a").Delay())
+}
+
+func (t *AdaptiveLimiterTest) Test_RateLimitHeaders() {
+	limiter := t.new(AdaptiveLimiterConfig{})
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "20")
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", "1")
+
+	limiter.ObserveResponse("a", http.StatusOK, header)
+	t.Assert().Equal(20.0, limiter.Rate("a"))
+	t.Assert().Equal(time.Second, limiter.Reserve("a").Delay())
+
+	header = http.Header{}
+	header.Set("RateLimit-Remaining", "0")
+	header.Set("RateLimit-Reset", strconv.FormatInt(t.clock.Now().Add(time.Hour).Unix()+1, 10))
+	limiter.ObserveResponse("b", http.StatusOK, header)
+	t.Assert().Greater(limiter.Reserve("b").Delay(), time.Hour)
+}
+
+func (t *AdaptiveLimiterTest) Test_Wait_Cancelled() {
+	limiter := t.new(AdaptiveLimiterConfig{MaxRPS: 1})
+	limiter.now = nil
+	t.Require().NoError(limiter.Wait(context.Background(), "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	t.Assert().ErrorIs(limiter.Wait(ctx, "a"), context.DeadlineExceeded)
+	t.Assert().False(limiter.Allow("a"))
+}
+
+func (t *AdaptiveLimiterTest) Test_ClientObservesResponses() {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	limiter := NewAdaptiveLimiter(AdaptiveLimiterConfig{MaxRPS: 1000})
+	defer limiter.Close()
+	_, status, err := New(srv.URL, "token").WithLimiter(limiter).Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	t.Require().NoError(err)
+	t.Assert().Equal(http.StatusOK, status)
+	t.Assert().Len(srv.bodies, 2)
+	t.Assert().Less(limiter.Rate("token"), 1000.0)
+	t.Assert().Equal(1000.0, limiter.Rate("other"))
+}
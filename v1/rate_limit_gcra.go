@@ -0,0 +1,194 @@
+package v1
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// GCRALimiter implements a sharded rate limiter using the generic cell rate algorithm.
+//
+// Unlike TokensBucket, which allows the whole maxRPS at the start of every second, GCRALimiter spreads
+// the requests evenly: every id has a bucket of burst tokens which is refilled by one token every 1/rps
+// seconds. This keeps the traffic smooth and avoids 429 responses at the window boundaries.
+type GCRALimiter struct {
+	interval        int64 // time to refill one token, in nanoseconds
+	tolerance       int64 // burst size expressed in time, in nanoseconds
+	unusedTokenTime int64 // in nanoseconds
+	checkTokenTime  time.Duration
+	shards          []*gcraShard
+	shardCount      uint32
+	lazyCleanup     bool
+	done            chan struct{}
+	closeOnce       sync.Once
+	sleep           sleeper
+	now             func() time.Time
+}
+
+// GCRALimiterOption configures the GCRALimiter.
+type GCRALimiterOption func(*GCRALimiter)
+
+type gcraShard struct {
+	// tats contains the theoretical arrival time of the next request for every id, in Unix nanoseconds.
+	tats        map[string]int64
+	nextCleanup int64 // used only with the lazy cleanup, in Unix nanoseconds
+	mu          sync.Mutex
+}
+
+// WithGCRALazyCleanup disables the background cleanup goroutine. The unused ids are removed from the shard
+// when it's accessed and at least checkTokenTime has passed since its previous cleanup.
+func WithGCRALazyCleanup() GCRALimiterOption {
+	return func(limiter *GCRALimiter) {
+		limiter.lazyCleanup = true
+	}
+}
+
+// NewGCRALimiter creates a sharded GCRA limiter which allows rps requests per second for every id
+// with bursts of up to burst requests. Zero burst is treated as 1, which means no bursts at all.
+// The unused ids are removed in the background goroutine which is stopped by Close, unless WithGCRALazyCleanup
+// option is provided.
+func NewGCRALimiter(
+	rps, burst uint32, unusedTokenTime, checkTokenTime time.Duration, opts ...GCRALimiterOption) *GCRALimiter {
+	shardCount := uint32(runtime.NumCPU() * shardsPerCoreMultiplier)
+	shards := make([]*gcraShard, shardCount)
+	for i := range shards {
+		shards[i] = &gcraShard{tats: make(map[string]int64)}
+	}
+
+	interval := int64(time.Second) / int64(max(rps, 1))
+	limiter := &GCRALimiter{
+		interval:        interval,
+		tolerance:       interval * int64(max(burst, 1)),
+		unusedTokenTime: unusedTokenTime.Nanoseconds(),
+		checkTokenTime:  checkTokenTime,
+		shards:          shards,
+		shardCount:      shardCount,
+		sleep:           realSleeper{},
+	}
+	for _, opt := range opts {
+		opt(limiter)
+	}
+
+	if !limiter.lazyCleanup {
+		limiter.done = make(chan struct{})
+		go limiter.cleanupRoutine()
+	}
+	return limiter
+}
+
+// Obtain request hit. Will throttle RPS.
+func (m *GCRALimiter) Obtain(id string) {
+	if delay := m.Reserve(id).Delay(); delay > 0 {
+		m.sleep.Sleep(delay)
+	}
+}
+
+// Wait blocks until the request is allowed or the context is done.
+func (m *GCRALimiter) Wait(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := m.Reserve(id)
+	if r.Delay() <= 0 {
+		return nil
+	}
+
+	if err := sleepContext(ctx, r.Delay()); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// Reserve takes the next token for the id, waiting for the refill if the bucket is empty.
+func (m *GCRALimiter) Reserve(id string) Reservation {
+	delay, tat, _ := m.take(id, true)
+	return Reservation{
+		delay:  delay,
+		cancel: func() { m.release(id, tat) },
+	}
+}
+
+// Allow takes the token only if the bucket is not empty.
+func (m *GCRALimiter) Allow(id string) bool {
+	_, _, ok := m.take(id, false)
+	return ok
+}
+
+// take moves the theoretical arrival time of the id forward by one interval and returns the time
+// to wait for the taken token along with the new theoretical arrival time.
+func (m *GCRALimiter) take(id string, reserve bool) (time.Duration, int64, bool) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := m.nowNano()
+	if m.lazyCleanup && now >= shard.nextCleanup {
+		m.cleanupShard(shard, now)
+		shard.nextCleanup = now + int64(m.checkTokenTime)
+	}
+
+	tat := max(shard.tats[id], now) + m.interval
+	delay := tat - now - m.tolerance
+	if delay > 0 && !reserve {
+		return 0, 0, false
+	}
+
+	shard.tats[id] = tat
+	return time.Duration(max(delay, 0)), tat, true
+}
+
+// release returns the token if no other token was taken for the id after it.
+func (m *GCRALimiter) release(id string, tat int64) {
+	shard := m.getShard(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if current, exists := shard.tats[id]; exists && current == tat {
+		shard.tats[id] = tat - m.interval
+	}
+}
+
+func (m *GCRALimiter) nowNano() int64 {
+	if m.now != nil {
+		return m.now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+func (m *GCRALimiter) getShard(id string) *gcraShard {
+	return m.shards[shardIndex(id, m.shardCount)]
+}
+
+func (m *GCRALimiter) cleanupRoutine() {
+	runCleanup(m.checkTokenTime, m.done, func(now int64) {
+		for _, shard := range m.shards {
+			shard.mu.Lock()
+			m.cleanupShard(shard, now)
+			shard.mu.Unlock()
+		}
+	})
+}
+
+// cleanupShard removes the unused ids from the shard. The shard must be locked.
+func (m *GCRALimiter) cleanupShard(shard *gcraShard, now int64) {
+	for id, tat := range shard.tats {
+		if now-tat >= m.unusedTokenTime {
+			delete(shard.tats, id)
+		}
+	}
+}
+
+// Close stops the background cleanup goroutine. Close is safe to call more than once.
+func (m *GCRALimiter) Close() error {
+	m.closeOnce.Do(func() {
+		if m.done != nil {
+			close(m.done)
+		}
+	})
+	return nil
+}
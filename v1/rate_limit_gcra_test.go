@@ -0,0 +1,237 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GCRALimiterTest struct {
+	suite.Suite
+}
+
+func TestGCRALimiter(t *testing.T) {
+	suite.Run(t, new(GCRALimiterTest))
+}
+
+func newTestGCRALimiter(rps, burst uint32, sleeper *fakeSleeper) *GCRALimiter {
+	limiter := &GCRALimiter{
+		interval:        int64(time.Second) / int64(rps),
+		tolerance:       int64(time.Second) / int64(rps) * int64(burst),
+		unusedTokenTime: int64(time.Hour),
+		checkTokenTime:  time.Minute,
+		shards:          []*gcraShard{{tats: make(map[string]int64)}},
+		shardCount:      1,
+		sleep:           sleeper,
+		now:             sleeper.Now,
+	}
+	return limiter
+}
+
+func (t *GCRALimiterTest) Test_NewGCRALimiter() {
+	limiter := NewGCRALimiter(10, 0, time.Hour, time.Hour)
+	t.Require().NotNil(limiter)
+	defer limiter.Close()
+	t.Assert().Implements((*ContextLimiter)(nil), limiter)
+}
+
+func (t *GCRALimiterTest) Test_Obtain_Burst() {
+	clock := &fakeSleeper{start: time.Now()}
+	limiter := newTestGCRALimiter(10, 5, clock)
+
+	for i := 0; i < 5; i++ {
+		limiter.Obtain("a")
+	}
+	t.Assert().Zero(clock.total.Load())
+
+	limiter.Obtain("a")
+	t.Assert().Equal(1, int(clock.total.Load()))
+	t.Assert().Equal(100*time.Millisecond, time.Duration(clock.elapsed.Load()))
+}
+
+func (t *GCRALimiterTest) Test_Obtain_Smooth() {
+	clock := &fakeSleeper{start: time.Now()}
+	limiter := newTestGCRALimiter(100, 1, clock)
+
+	for i := 0; i < 301; i++ {
+		limiter.Obtain("a")
+	}
+	t.Assert().Equal(300, int(clock.total.Load()))
+	t.Assert().Equal(3*time.Second, time.Duration(clock.elapsed.Load()))
+}
+
+func (t *GCRALimiterTest) Test_Refill() {
+	clock := &fakeSleeper{start: time.Now()}
+	limiter := newTestGCRALimiter(10, 2, clock)
+
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().False(limiter.Allow("a"))
+	t.Assert().True(limiter.Allow("b"))
+
+	clock.Sleep(100 * time.Millisecond)
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().False(limiter.Allow("a"))
+
+	clock.Sleep(time.Second)
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().False(limiter.Allow("a"), "idle time should not accumulate more tokens than burst")
+}
+
+func (t *GCRALimiterTest) Test_Reserve_Cancel() {
+	clock := &fakeSleeper{start: time.Now()}
+	limiter := newTestGCRALimiter(10, 1, clock)
+
+	t.Assert().Zero(limiter.Reserve("a").Delay())
+	r := limiter.Reserve("a")
+	t.Assert().Equal(100*time.Millisecond, r.Delay())
+	r.Cancel()
+	t.Assert().Equal(100*time.Millisecond, limiter.Reserve("a").Delay())
+}
+
+func (t *GCRALimiterTest) Test_Wait() {
+	limiter := newTestGCRALimiter(1, 1, &fakeSleeper{start: time.Now()})
+	limiter.now = nil
+	t.Require().NoError(limiter.Wait(context.Background(), "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	t.Assert().ErrorIs(limiter.Wait(ctx, "a"), context.DeadlineExceeded)
+	t.Assert().Less(time.Since(start), 500*time.Millisecond)
+	t.Assert().False(limiter.Allow("a"))
+}
+
+// benchmarkLimiterIDs contains sub-benchmark names and the number of distinct ids used by them.
+var benchmarkLimiterIDs = []struct {
+	name string
+	ids  int
+}{{"single-id", 1}, {"many-ids", 16}}
+
+// benchmarkLimiterThroughput measures the overhead of the limiter which is never throttled.
+func benchmarkLimiterThroughput(b *testing.B, limiter Limiter, ids int) {
+	defer limiter.(io.Closer).Close()
+	var n atomic.Uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		id := strconv.FormatUint(n.Add(1)%uint64(ids), 10)
+		for pb.Next() {
+			limiter.Obtain(id)
+		}
+	})
+}
+
+func BenchmarkTokensBucket_Throughput(b *testing.B) {
+	for _, bench := range benchmarkLimiterIDs {
+		b.Run(bench.name, func(b *testing.B) {
+			benchmarkLimiterThroughput(b, NewTokensBucket(1e9, time.Hour, time.Hour), bench.ids)
+		})
+	}
+}
+
+func BenchmarkGCRALimiter_Throughput(b *testing.B) {
+	for _, bench := range benchmarkLimiterIDs {
+		b.Run(bench.name, func(b *testing.B) {
+			benchmarkLimiterThroughput(b, NewGCRALimiter(1e9, 1e9, time.Hour, time.Hour), bench.ids)
+		})
+	}
+}
+
+// benchmarkLimiterFairness runs one throttled goroutine per id which compete for b.N grants in total and reports
+// the ratio between the largest and the smallest number of grants received by a single id.
+// A ratio close to 1 means that no id is starved by the others.
+func benchmarkLimiterFairness(b *testing.B, limiter Limiter) {
+	const ids = 16
+	defer limiter.(io.Closer).Close()
+
+	var (
+		wg        sync.WaitGroup
+		remaining atomic.Int64
+		counts    [ids]atomic.Uint64
+		start     = make(chan struct{})
+	)
+	remaining.Store(int64(b.N))
+	for i := range counts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			<-start
+			for remaining.Add(-1) >= 0 {
+				limiter.Obtain(id)
+				counts[i].Add(1)
+			}
+		}(i)
+	}
+	b.ResetTimer()
+	close(start)
+	wg.Wait()
+	b.StopTimer()
+
+	least, most := counts[0].Load(), counts[0].Load()
+	for i := range counts {
+		least, most = min(least, counts[i].Load()), max(most, counts[i].Load())
+	}
+	b.ReportMetric(float64(most)/float64(max(least, 1)), "max/min-grants")
+}
+
+func BenchmarkTokensBucket_Fairness(b *testing.B) {
+	benchmarkLimiterFairness(b, NewTokensBucket(1000, time.Hour, time.Hour))
+}
+
+func BenchmarkGCRALimiter_Fairness(b *testing.B) {
+	benchmarkLimiterFairness(b, NewGCRALimiter(1000, 1, time.Hour, time.Hour))
+}
+
+// benchmarkLimiterBurst sends requests as fast as the limiter allows using the fake clock and reports
+// the largest number of requests sent within 100ms. Lower burst means smoother traffic.
+func benchmarkLimiterBurst(b *testing.B, limiter func(clock *fakeSleeper) Limiter) {
+	const rps, window = 100, 100 * time.Millisecond
+
+	var maxBurst int
+	for i := 0; i < b.N; i++ {
+		clock := &fakeSleeper{start: time.Now()}
+		l := limiter(clock)
+		sent := make([]time.Time, 0, rps*3)
+		for j := 0; j < cap(sent); j++ {
+			l.Obtain("a")
+			sent = append(sent, clock.Now())
+		}
+
+		for j, from := 0, 0; j < len(sent); j++ {
+			for sent[j].Sub(sent[from]) >= window {
+				from++
+			}
+			maxBurst = max(maxBurst, j-from+1)
+		}
+	}
+
+	b.ReportMetric(float64(maxBurst), "max-req/100ms")
+}
+
+func BenchmarkTokensBucket_Burst(b *testing.B) {
+	benchmarkLimiterBurst(b, func(clock *fakeSleeper) Limiter {
+		return &TokensBucket{
+			maxRPS:     MaxRPS,
+			shards:     []*tokenShard{{tokens: make(map[string]*token)}},
+			shardCount: 1,
+			sleep:      clock,
+			now:        clock.Now,
+		}
+	})
+}
+
+func BenchmarkGCRALimiter_Burst(b *testing.B) {
+	benchmarkLimiterBurst(b, func(clock *fakeSleeper) Limiter {
+		return newTestGCRALimiter(MaxRPS, MaxRPS/10, clock)
+	})
+}
@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// GroupLimiter is the Limiter which uses separate limits for some endpoint groups.
+// MgClient waits for the group limiter using the token and the group name as the key, for example
+// "token:history", so the requests to different groups never share a bucket. Requests to the groups
+// without a separate limiter are limited by the GroupLimiter itself using the token as the key.
+type GroupLimiter interface {
+	Limiter
+	// Group returns the limiter of the endpoint group and false if the group has no separate limiter.
+	Group(group EndpointGroup) (Limiter, bool)
+}
+
+// EndpointGroupLimiter is the GroupLimiter which uses the Default limiter for all endpoint groups
+// except the ones present in Groups.
+//
+// The same limiter can be used for several groups, e.g. one TokensBucket for both EndpointHistory
+// and EndpointFiles: the groups will still have separate buckets with the limit of that TokensBucket.
+type EndpointGroupLimiter struct {
+	// Default is used for the groups which are not present in Groups. Requests are not limited if it's nil.
+	Default Limiter
+	// Groups contains the limiters of the endpoint groups.
+	Groups map[EndpointGroup]Limiter
+}
+
+// NewEndpointGroupLimiter creates the EndpointGroupLimiter.
+func NewEndpointGroupLimiter(defaultLimiter Limiter, groups map[EndpointGroup]Limiter) *EndpointGroupLimiter {
+	return &EndpointGroupLimiter{Default: defaultLimiter, Groups: groups}
+}
+
+// Obtain request hit using the Default limiter.
+func (l *EndpointGroupLimiter) Obtain(id string) {
+	if l.Default != nil {
+		l.Default.Obtain(id)
+	}
+}
+
+// Wait waits for the Default limiter or until the context is done.
+func (l *EndpointGroupLimiter) Wait(ctx context.Context, id string) error {
+	if l.Default == nil {
+		return ctx.Err()
+	}
+	return waitLimiter(ctx, l.Default, id)
+}
+
+// Reserve reserves the slot in the Default limiter. If the Default limiter is not a ContextLimiter,
+// Reserve blocks until the request is allowed.
+func (l *EndpointGroupLimiter) Reserve(id string) Reservation {
+	if limiter, ok := l.Default.(ContextLimiter); ok {
+		return limiter.Reserve(id)
+	}
+	l.Obtain(id)
+	return Reservation{}
+}
+
+// Allow takes the slot in the Default limiter. If the Default limiter is not a ContextLimiter,
+// Allow blocks until the request is allowed.
+func (l *EndpointGroupLimiter) Allow(id string) bool {
+	if limiter, ok := l.Default.(ContextLimiter); ok {
+		return limiter.Allow(id)
+	}
+	l.Obtain(id)
+	return true
+}
+
+// Group implements GroupLimiter.
+func (l *EndpointGroupLimiter) Group(group EndpointGroup) (Limiter, bool) {
+	limiter, ok := l.Groups[group]
+	return limiter, ok && limiter != nil
+}
+
+// ObserveResponse passes the response to the Default limiter if it implements ResponseObserver. MgClient passes
+// the responses of the groups with a separate limiter directly to that limiter.
+func (l *EndpointGroupLimiter) ObserveResponse(id string, statusCode int, header http.Header) {
+	if observer, ok := l.Default.(ResponseObserver); ok {
+		observer.ObserveResponse(id, statusCode, header)
+	}
+}
+
+// Close closes the Default limiter and the limiters of the Groups which implement io.Closer.
+// The limiter used for several groups is closed once.
+func (l *EndpointGroupLimiter) Close() error {
+	closed := make(map[io.Closer]struct{}, len(l.Groups)+1)
+	errs := make([]error, 0, len(l.Groups)+1)
+
+	closeLimiter := func(limiter Limiter) {
+		closer, ok := limiter.(io.Closer)
+		if !ok {
+			return
+		}
+		if _, ok := closed[closer]; ok {
+			return
+		}
+		closed[closer] = struct{}{}
+		errs = append(errs, closer.Close())
+	}
+
+	closeLimiter(l.Default)
+	for _, limiter := range l.Groups {
+		closeLimiter(limiter)
+	}
+
+	return errors.Join(errs...)
+}
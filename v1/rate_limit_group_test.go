@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyRecordingLimiter struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (l *keyRecordingLimiter) Obtain(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, id)
+}
+
+func TestRouteEndpointGroup(t *testing.T) {
+	cases := map[string]EndpointGroup{
+		"/messages":                       EndpointMessages,
+		"/messages/ack":                   EndpointMessages,
+		"/messages/reaction":              EndpointMessages,
+		"/messages/history":               EndpointHistory,
+		"/files/upload":                   EndpointFiles,
+		"/files/{id}":                     EndpointFiles,
+		"/channels":                       EndpointChannels,
+		"/channels/{id}":                  EndpointChannels,
+		"/templates":                      EndpointTemplates,
+		"/channels/{id}/templates":        EndpointTemplates,
+		"/channels/{id}/templates/{code}": EndpointTemplates,
+		"/unknown":                        EndpointMessages,
+	}
+
+	for route, group := range cases {
+		assert.Equal(t, group, RouteEndpointGroup(route), route)
+	}
+}
+
+func TestEndpointGroupLimiter_Keys(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	def, history, files := &keyRecordingLimiter{}, &keyRecordingLimiter{}, &keyRecordingLimiter{}
+	c := New(srv.URL, "token").WithLimiter(NewEndpointGroupLimiter(def, map[EndpointGroup]Limiter{
+		EndpointHistory: history,
+		EndpointFiles:   files,
+	}))
+
+	_, _, err := c.Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+	_, _, err = c.MessagesHistory(SendHistoryMessageRequest{})
+	require.NoError(t, err)
+	_, _, err = c.UploadFileByURL(UploadFileByUrlRequest{Url: "https://example.com/file"})
+	require.NoError(t, err)
+	_, err = c.ActivateTemplate(1, ActivateTemplateRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"token", "token"}, def.keys)
+	assert.Equal(t, []string{"token:history"}, history.keys)
+	assert.Equal(t, []string{"token:files"}, files.keys)
+}
+
+func TestEndpointGroupLimiter_HistoryDoesNotStarveMessages(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	bucket := NewGCRALimiter(1, 1, time.Hour, time.Hour)
+	defer bucket.Close()
+	c := New(srv.URL, "token").WithLimiter(NewEndpointGroupLimiter(bucket, map[EndpointGroup]Limiter{
+		EndpointHistory: bucket,
+	}))
+
+	_, _, err := c.MessagesHistory(SendHistoryMessageRequest{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, _, err = c.MessagesHistoryContext(ctx, SendHistoryMessageRequest{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, status, err := c.MessagesContext(context.Background(), SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestEndpointGroupLimiter_NilDefault(t *testing.T) {
+	limiter := NewEndpointGroupLimiter(nil, nil)
+	limiter.Obtain("token")
+	assert.True(t, limiter.Allow("token"))
+	assert.Zero(t, limiter.Reserve("token").Delay())
+	assert.NoError(t, limiter.Wait(context.Background(), "token"))
+
+	_, ok := limiter.Group(EndpointFiles)
+	assert.False(t, ok)
+}
+
+type closeCountingLimiter struct {
+	keyRecordingLimiter
+	closed int
+	err    error
+}
+
+func (l *closeCountingLimiter) Close() error {
+	l.closed++
+	return l.err
+}
+
+func TestEndpointGroupLimiter_ObserveResponse(t *testing.T) {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	def := NewAdaptiveLimiter(AdaptiveLimiterConfig{MaxRPS: 1000})
+	defer def.Close()
+	history := NewAdaptiveLimiter(AdaptiveLimiterConfig{MaxRPS: 1000})
+	defer history.Close()
+
+	c := retryingClient(srv.URL).WithLimiter(NewEndpointGroupLimiter(def, map[EndpointGroup]Limiter{
+		EndpointHistory: history,
+	}))
+
+	_, _, err := c.Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+	assert.Less(t, def.Rate("token"), 1000.0)
+	assert.Equal(t, 1000.0, history.Rate("token:history"))
+}
+
+func TestEndpointGroupLimiter_Close(t *testing.T) {
+	def := &closeCountingLimiter{}
+	shared := &closeCountingLimiter{err: errors.New("close failed")}
+	limiter := NewEndpointGroupLimiter(def, map[EndpointGroup]Limiter{
+		EndpointHistory:  shared,
+		EndpointFiles:    shared,
+		EndpointMessages: &keyRecordingLimiter{},
+	})
+
+	require.EqualError(t, limiter.Close(), "close failed")
+	assert.Equal(t, 1, def.closed)
+	assert.Equal(t, 1, shared.closed, "the limiter shared by the groups should be closed once")
+
+	assert.NoError(t, NewEndpointGroupLimiter(nil, nil).Close())
+}
@@ -0,0 +1,344 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSharedStoreTimeout    = time.Second
+	defaultSharedReserveWindows  = 60
+	defaultSharedReserveAttempts = 3
+	memoryCounterSweepInterval   = time.Minute
+)
+
+var errSharedWindowsExhausted = errors.New("shared limiter windows are exhausted")
+
+// CounterStore keeps the request counters shared between the processes.
+type CounterStore interface {
+	// Add atomically adds delta to the counter with the provided key and returns the new value.
+	// The counter is created with zero value if it does not exist and is removed after ttl.
+	Add(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// SharedLimiter is the fixed window limiter which keeps its counters in the CounterStore,
+// so all replicas using the same store share one budget per token.
+//
+// Requests over the limit reserve the slot in one of the next windows. If the store fails,
+// the Fallback limiter is used; the requests are not limited at all if it's nil, so every replica
+// may send MaxRPS requests while the store is down. The counter keys contain
+// the SHA-256 hash of the id instead of the id itself.
+type SharedLimiter struct {
+	// Store keeps the counters.
+	Store CounterStore
+	// MaxRPS is the number of requests allowed per Window. MaxRPS constant is used by default.
+	MaxRPS int64
+	// Window is the length of the fixed window. Default is one second.
+	Window time.Duration
+	// Timeout limits the store requests made without a context. Default is one second.
+	Timeout time.Duration
+	// Prefix is added to the counter keys, so several limiters can use the same store.
+	Prefix string
+	// Fallback is used when the store fails or when the next windows have no free slots.
+	Fallback Limiter
+	// OnError is called with every store error.
+	OnError func(err error)
+	now     func() time.Time
+	sleep   sleeper
+}
+
+// NewSharedLimiter creates the SharedLimiter with the provided store and limit per second.
+//
+// The returned limiter has no Fallback and doesn't limit the requests while the store is unavailable.
+// Set the Fallback to the local limiter to keep limiting each replica in this case:
+//
+//	limiter := v1.NewSharedLimiter(store, v1.MaxRPS)
+//	limiter.Fallback = v1.NewTokensBucket(v1.MaxRPS, time.Minute, time.Minute)
+func NewSharedLimiter(store CounterStore, maxRPS int64) *SharedLimiter {
+	return &SharedLimiter{Store: store, MaxRPS: maxRPS}
+}
+
+// Obtain request hit. Will throttle RPS.
+func (l *SharedLimiter) Obtain(id string) {
+	if delay := l.Reserve(id).Delay(); delay > 0 {
+		l.sleeper().Sleep(delay)
+	}
+}
+
+// Wait blocks until the request is allowed or the context is done. Like Reserve, it uses the Fallback limiter
+// if there are no free slots within the next 60 windows.
+func (l *SharedLimiter) Wait(ctx context.Context, id string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := l.reserve(ctx, id)
+		switch {
+		case errors.Is(err, errSharedWindowsExhausted) && l.Fallback == nil:
+			if err := sleepContext(ctx, l.window()); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return l.fallbackWait(ctx, id)
+		}
+
+		if err := sleepContext(ctx, r.Delay()); err != nil {
+			r.Cancel()
+			return err
+		}
+		return nil
+	}
+}
+
+// Reserve takes the slot in the current window or in the first of the next windows which is not exhausted.
+// If there are no free slots within the next 60 windows, the Fallback limiter is used, or Reserve waits
+// for the next window and tries again if there is no Fallback.
+func (l *SharedLimiter) Reserve(id string) Reservation {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout())
+		r, err := l.reserve(ctx, id)
+		cancel()
+
+		switch {
+		case errors.Is(err, errSharedWindowsExhausted) && l.Fallback == nil:
+			l.sleeper().Sleep(l.window())
+			continue
+		case err != nil:
+			if limiter, isContext := l.Fallback.(ContextLimiter); isContext {
+				return limiter.Reserve(id)
+			}
+			if l.Fallback != nil {
+				l.Fallback.Obtain(id)
+			}
+			return Reservation{}
+		}
+		return r
+	}
+}
+
+// Allow takes the slot only if the current window is not exhausted.
+func (l *SharedLimiter) Allow(id string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout())
+	defer cancel()
+
+	now := l.nowTime()
+	_, n, err := l.take(ctx, id, l.windowIndex(now), now)
+	if err != nil {
+		if limiter, ok := l.Fallback.(ContextLimiter); ok {
+			return limiter.Allow(id)
+		}
+		return true
+	}
+	return n <= l.maxRPS()
+}
+
+// reserve takes the slot in the current window or in one of the next windows. Besides the window counters,
+// the store keeps the index of the first window which may have free slots, so the requests over the limit
+// skip the exhausted windows instead of checking them one by one. It returns errSharedWindowsExhausted
+// if the free slot is not found within defaultSharedReserveWindows windows.
+func (l *SharedLimiter) reserve(ctx context.Context, id string) (Reservation, error) {
+	window, maxRPS := l.window(), l.maxRPS()
+	now := l.nowTime()
+	current := l.windowIndex(now)
+
+	r, n, err := l.take(ctx, id, current, now)
+	if err != nil || n <= maxRPS {
+		return r, err
+	}
+
+	nextKey := l.idKey(id) + "next"
+	nextTTL := time.Duration(defaultSharedReserveWindows+1) * window
+	next, err := l.Store.Add(ctx, nextKey, 0, nextTTL)
+	if err != nil {
+		l.handleError(err)
+		return Reservation{}, err
+	}
+
+	index := max(next, current+1)
+	for attempt := 0; attempt < defaultSharedReserveAttempts; attempt++ {
+		if index-current > defaultSharedReserveWindows {
+			break
+		}
+
+		r, n, err = l.take(ctx, id, index, now)
+		if err != nil {
+			return r, err
+		}
+		if n <= maxRPS {
+			if n == maxRPS {
+				index++
+			}
+			l.advance(nextKey, next, index, nextTTL)
+			return r, nil
+		}
+		index++
+	}
+
+	l.advance(nextKey, next, index, nextTTL)
+	return Reservation{}, errSharedWindowsExhausted
+}
+
+// take increments the counter of the window with the provided index. The slot is released at once
+// if the window is exhausted, otherwise it's returned as the Reservation.
+func (l *SharedLimiter) take(ctx context.Context, id string, index int64, now time.Time) (Reservation, int64, error) {
+	window := l.window()
+	start := time.Unix(0, index*int64(window))
+	key, ttl := l.key(id, start), start.Add(window).Sub(now)
+
+	n, err := l.Store.Add(ctx, key, 1, ttl)
+	if err != nil {
+		l.handleError(err)
+		return Reservation{}, 0, err
+	}
+
+	if n > l.maxRPS() {
+		l.release(key, ttl)
+		return Reservation{}, n, nil
+	}
+	return Reservation{delay: max(start.Sub(now), 0), cancel: func() { l.release(key, ttl) }}, n, nil
+}
+
+// advance moves the index of the first window which may have free slots. Concurrent requests can move it
+// too far; the skipped slots are still used by the requests made when their windows become current.
+func (l *SharedLimiter) advance(key string, seen, index int64, ttl time.Duration) {
+	if index <= seen {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout())
+	defer cancel()
+
+	if _, err := l.Store.Add(ctx, key, index-seen, ttl); err != nil {
+		l.handleError(err)
+	}
+}
+
+// release returns the slot to the counter. The ttl must not be shorter than the one used to take the slot.
+func (l *SharedLimiter) release(key string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout())
+	defer cancel()
+
+	if _, err := l.Store.Add(ctx, key, -1, ttl); err != nil {
+		l.handleError(err)
+	}
+}
+
+func (l *SharedLimiter) fallbackWait(ctx context.Context, id string) error {
+	if l.Fallback == nil {
+		return nil
+	}
+	return waitLimiter(ctx, l.Fallback, id)
+}
+
+func (l *SharedLimiter) handleError(err error) {
+	if l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// windowIndex returns the number of the windows passed since the Unix epoch.
+func (l *SharedLimiter) windowIndex(now time.Time) int64 {
+	return now.UnixNano() / int64(l.window())
+}
+
+// key returns the counter key of the window.
+func (l *SharedLimiter) key(id string, start time.Time) string {
+	return l.idKey(id) + strconv.FormatInt(start.UnixNano(), 10)
+}
+
+// idKey returns the prefix of the id keys. The id is hashed, so the tokens are not exposed to the store.
+func (l *SharedLimiter) idKey(id string) string {
+	return l.Prefix + HashLimiterID(id) + ":"
+}
+
+func (l *SharedLimiter) maxRPS() int64 {
+	if l.MaxRPS <= 0 {
+		return MaxRPS
+	}
+	return l.MaxRPS
+}
+
+func (l *SharedLimiter) window() time.Duration {
+	if l.Window <= 0 {
+		return time.Second
+	}
+	return l.Window
+}
+
+func (l *SharedLimiter) timeout() time.Duration {
+	if l.Timeout <= 0 {
+		return defaultSharedStoreTimeout
+	}
+	return l.Timeout
+}
+
+func (l *SharedLimiter) nowTime() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+func (l *SharedLimiter) sleeper() sleeper {
+	if l.sleep != nil {
+		return l.sleep
+	}
+	return realSleeper{}
+}
+
+// MemoryCounterStore is the in-memory CounterStore. It can be used to share the limits between
+// the clients of one process and as the storage of the CounterServer.
+type MemoryCounterStore struct {
+	mu        sync.Mutex
+	counters  map[string]*memoryCounter
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// NewMemoryCounterStore creates the MemoryCounterStore.
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{counters: make(map[string]*memoryCounter), now: time.Now}
+}
+
+// Add implements CounterStore.
+func (s *MemoryCounterStore) Add(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, counter := range s.counters {
+			if !now.Before(counter.expiresAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.nextSweep = now.Add(memoryCounterSweepInterval)
+	}
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.expiresAt) {
+		counter = &memoryCounter{}
+		s.counters[key] = counter
+	}
+
+	counter.value += delta
+	counter.expiresAt = now.Add(ttl)
+	return counter.value, nil
+}
+
+// Len returns the number of the stored counters.
+func (s *MemoryCounterStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counters)
+}
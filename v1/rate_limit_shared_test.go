@@ -0,0 +1,246 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingCounterStore struct{}
+
+func (s failingCounterStore) Add(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errors.New("store is down")
+}
+
+func newTestSharedLimiter(store CounterStore, maxRPS int64) (*SharedLimiter, *fakeSleeper) {
+	clock := &fakeSleeper{start: time.Unix(1000, 0)}
+	limiter := NewSharedLimiter(store, maxRPS)
+	limiter.now, limiter.sleep = clock.Now, clock
+	return limiter, clock
+}
+
+func TestSharedLimiter_SharedBudget(t *testing.T) {
+	store := NewMemoryCounterStore()
+	first, clock := newTestSharedLimiter(store, 2)
+	second := NewSharedLimiter(store, 2)
+	second.now = clock.Now
+
+	assert.True(t, first.Allow("token"))
+	assert.True(t, second.Allow("token"))
+	assert.False(t, first.Allow("token"))
+	assert.False(t, second.Allow("token"))
+	assert.True(t, second.Allow("other"))
+
+	clock.Sleep(time.Second)
+	assert.True(t, first.Allow("token"))
+}
+
+func TestSharedLimiter_Reserve(t *testing.T) {
+	limiter, clock := newTestSharedLimiter(NewMemoryCounterStore(), 1)
+	clock.Sleep(250 * time.Millisecond)
+
+	assert.Zero(t, limiter.Reserve("token").Delay())
+	next := limiter.Reserve("token")
+	assert.Equal(t, 750*time.Millisecond, next.Delay())
+	assert.Equal(t, 1750*time.Millisecond, limiter.Reserve("token").Delay())
+
+	next.Cancel()
+	assert.Equal(t, 2750*time.Millisecond, limiter.Reserve("token").Delay(), "queued requests are not reordered")
+
+	clock.Sleep(750 * time.Millisecond)
+	assert.Zero(t, limiter.Reserve("token").Delay(), "cancelled slot should be free in its window")
+}
+
+type countingCounterStore struct {
+	CounterStore
+	mu   sync.Mutex
+	keys []string
+}
+
+func (s *countingCounterStore) Add(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+	return s.CounterStore.Add(ctx, key, delta, ttl)
+}
+
+func TestSharedLimiter_ReserveOverload(t *testing.T) {
+	store := &countingCounterStore{CounterStore: NewMemoryCounterStore()}
+	limiter, _ := newTestSharedLimiter(store, 2)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, time.Duration(i/2)*time.Second, limiter.Reserve("token").Delay(), i)
+	}
+	assert.LessOrEqual(t, len(store.keys), 5*100, "queued requests should skip the exhausted windows")
+	for _, key := range store.keys {
+		assert.NotContains(t, key, "token")
+	}
+}
+
+func TestSharedLimiter_WindowsExhausted(t *testing.T) {
+	limiter, clock := newTestSharedLimiter(NewMemoryCounterStore(), 1)
+	for i := 0; i <= defaultSharedReserveWindows; i++ {
+		limiter.Reserve("token")
+	}
+
+	fallback := &keyRecordingLimiter{}
+	limiter.Fallback = fallback
+	assert.Zero(t, limiter.Reserve("token").Delay())
+	assert.Equal(t, []string{"token"}, fallback.keys, "fallback should be used when all windows are taken")
+	assert.Zero(t, clock.total.Load())
+
+	limiter.Fallback = nil
+	assert.Equal(t, time.Duration(defaultSharedReserveWindows)*time.Second, limiter.Reserve("token").Delay())
+	assert.Equal(t, uint32(1), clock.total.Load(), "reserve should wait for the next window")
+}
+
+func TestSharedLimiter_Obtain(t *testing.T) {
+	limiter, clock := newTestSharedLimiter(NewMemoryCounterStore(), 100)
+
+	for i := 0; i < 301; i++ {
+		limiter.Obtain("token")
+	}
+	assert.Equal(t, 3, int(clock.total.Load()))
+}
+
+func TestSharedLimiter_WaitCancelled(t *testing.T) {
+	store := NewMemoryCounterStore()
+	limiter := NewSharedLimiter(store, 1)
+	limiter.Window = time.Hour
+	require.NoError(t, limiter.Wait(context.Background(), "token"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "token"), context.DeadlineExceeded)
+
+	next := time.Now().Truncate(time.Hour).Add(time.Hour)
+	value, err := store.Add(context.Background(), limiter.key("token", next), 0, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, value, "cancelled wait should return the reserved slot")
+}
+
+func TestSharedLimiter_StoreFailure(t *testing.T) {
+	var errs []error
+	limiter := NewSharedLimiter(failingCounterStore{}, 1)
+	limiter.OnError = func(err error) { errs = append(errs, err) }
+
+	assert.True(t, limiter.Allow("token"))
+	assert.NoError(t, limiter.Wait(context.Background(), "token"))
+	assert.Len(t, errs, 2)
+
+	fallback := &keyRecordingLimiter{}
+	limiter.Fallback = fallback
+	limiter.Obtain("token")
+	assert.Equal(t, []string{"token"}, fallback.keys)
+}
+
+func TestMemoryCounterStore_Expiration(t *testing.T) {
+	store := NewMemoryCounterStore()
+	clock := &fakeSleeper{start: time.Now()}
+	store.now = clock.Now
+
+	value, _ := store.Add(context.Background(), "a", 1, time.Second)
+	assert.Equal(t, int64(1), value)
+	value, _ = store.Add(context.Background(), "a", 2, time.Second)
+	assert.Equal(t, int64(3), value)
+
+	clock.Sleep(time.Second)
+	value, _ = store.Add(context.Background(), "a", 1, time.Second)
+	assert.Equal(t, int64(1), value)
+
+	_, _ = store.Add(context.Background(), "b", 1, time.Second)
+	clock.Sleep(2 * memoryCounterSweepInterval)
+	_, _ = store.Add(context.Background(), "c", 1, time.Second)
+	assert.Equal(t, 1, store.Len())
+}
+
+func startCounterServer(t *testing.T, network, address string) (*CounterServer, net.Listener) {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+
+	server := &CounterServer{}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+	t.Cleanup(func() {
+		require.NoError(t, server.Close())
+		assert.ErrorIs(t, <-done, net.ErrClosed)
+	})
+
+	return server, listener
+}
+
+func TestCounterServer_TCP(t *testing.T) {
+	_, listener := startCounterServer(t, "tcp", "127.0.0.1:0")
+	client := NewCounterClient("tcp", listener.Addr().String())
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Add(context.Background(), "key", 1, time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	value, err := client.Add(context.Background(), "key", 0, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), value)
+
+	_, err = client.Add(context.Background(), "bad key", 1, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestCounterServer_UnixSharedLimiter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "limiter.sock")
+	startCounterServer(t, "unix", socket)
+
+	first, second := NewCounterClient("unix", socket), NewCounterClient("unix", socket)
+	defer first.Close()
+	defer second.Close()
+
+	a, b := NewSharedLimiter(first, 1), NewSharedLimiter(second, 1)
+	a.Window, b.Window = time.Hour, time.Hour
+
+	assert.True(t, a.Allow("token"))
+	assert.False(t, b.Allow("token"))
+}
+
+func TestCounterServer_InvalidRequest(t *testing.T) {
+	_, listener := startCounterServer(t, "tcp", "127.0.0.1:0")
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET key\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), "ERR invalid request")
+}
+
+func TestCounterClient_Closed(t *testing.T) {
+	client := NewCounterClient("tcp", "127.0.0.1:0")
+	require.NoError(t, client.Close())
+
+	_, err := client.Add(context.Background(), "key", 1, time.Second)
+	assert.ErrorIs(t, err, ErrCounterClientClosed)
+}
+
+func TestCounterTTL(t *testing.T) {
+	assert.Equal(t, int64(0), counterTTL(0))
+	assert.Equal(t, int64(1), counterTTL(time.Microsecond))
+	assert.Equal(t, int64(1), counterTTL(time.Millisecond))
+	assert.Equal(t, int64(751), counterTTL(750*time.Millisecond+time.Nanosecond))
+}
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ThrottleFunc is called every time the limiter makes the request wait. The id is hashed by HashLimiterID,
+// so the tokens are not exposed to the metrics. It's called synchronously before the wait, so it should not block.
+type ThrottleFunc func(id string, wait time.Duration)
+
+// LimiterStats contains the TokensBucket statistics.
+type LimiterStats struct {
+	// ShardTokens contains the number of the tracked tokens in every shard.
+	ShardTokens []int
+	// Tokens contains the usage of the current window for every tracked token. It's keyed by HashLimiterID
+	// of the token.
+	Tokens map[string]TokenUsage
+	// Waits is the number of the throttled requests.
+	Waits uint64
+	// TotalWait is the cumulative time the throttled requests were told to wait.
+	TotalWait time.Duration
+	// MaxWait is the longest wait of a throttled request.
+	MaxWait time.Duration
+	// WaitHistogram contains the number of the throttled requests grouped by the wait time.
+	WaitHistogram []WaitBucket
+}
+
+// WaitBucket is the bucket of the wait time histogram.
+type WaitBucket struct {
+	// UpperBound is the longest wait counted in the bucket. It's math.MaxInt64 for the last bucket.
+	UpperBound time.Duration
+	// Count is the number of the waits longer than UpperBound of the previous bucket and not longer than UpperBound.
+	// The counts are not cumulative.
+	Count uint64
+}
+
+// waitBuckets contains the upper bounds of the wait time histogram buckets except the last one.
+var waitBuckets = [...]time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// TokenUsage contains the window usage of the token.
+type TokenUsage struct {
+	// Requests is the number of requests made or reserved in the window.
+	Requests uint32
+	// Limit is the maximum number of requests in the window.
+	Limit uint32
+	// WindowStart is the start of the window. It's in the future if the requests were reserved in the next window.
+	WindowStart time.Time
+}
+
+// WithThrottleCallback sets the callback which is called every time the TokensBucket throttles a request.
+func WithThrottleCallback(fn ThrottleFunc) TokensBucketOption {
+	return func(bucket *TokensBucket) {
+		bucket.onThrottle = fn
+	}
+}
+
+// Stats returns the current statistics of the limiter.
+func (m *TokensBucket) Stats() LimiterStats {
+	stats := LimiterStats{
+		ShardTokens: make([]int, len(m.shards)),
+		Tokens:      make(map[string]TokenUsage),
+		Waits:       m.stats.waits.Load(),
+		TotalWait:   time.Duration(m.stats.total.Load()),
+		MaxWait:     time.Duration(m.stats.max.Load()),
+	}
+	stats.WaitHistogram = m.stats.histogram()
+
+	for i, shard := range m.shards {
+		shard.mu.Lock()
+		stats.ShardTokens[i] = len(shard.tokens)
+		for id, item := range shard.tokens {
+			stats.Tokens[HashLimiterID(id)] = TokenUsage{
+				Requests:    atomic.LoadUint32(&item.rps),
+				Limit:       m.maxRPS,
+				WindowStart: time.Unix(0, item.lastUse),
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	return stats
+}
+
+func (m *TokensBucket) throttled(id string, wait time.Duration) {
+	m.stats.record(wait)
+	if m.onThrottle != nil {
+		m.onThrottle(HashLimiterID(id), wait)
+	}
+}
+
+// waitStats collects the wait statistics without locks.
+type waitStats struct {
+	waits atomic.Uint64
+	total atomic.Int64
+	max   atomic.Int64
+	// buckets contains the counts of the waits for every bound from waitBuckets and one more for the longer waits.
+	buckets [len(waitBuckets) + 1]atomic.Uint64
+}
+
+func (s *waitStats) record(wait time.Duration) {
+	s.waits.Add(1)
+	s.total.Add(int64(wait))
+	s.buckets[sort.Search(len(waitBuckets), func(i int) bool { return wait <= waitBuckets[i] })].Add(1)
+	for {
+		current := s.max.Load()
+		if int64(wait) <= current || s.max.CompareAndSwap(current, int64(wait)) {
+			return
+		}
+	}
+}
+
+func (s *waitStats) histogram() []WaitBucket {
+	histogram := make([]WaitBucket, len(s.buckets))
+	for i := range histogram {
+		histogram[i] = WaitBucket{UpperBound: math.MaxInt64, Count: s.buckets[i].Load()}
+		if i < len(waitBuckets) {
+			histogram[i].UpperBound = waitBuckets[i]
+		}
+	}
+	return histogram
+}
+
+// HashLimiterID returns the hex encoded SHA-256 hash of the limiter id. It's used instead of the id
+// everywhere the limiters expose the ids, e.g. in LimiterStats, because the ids contain the transport tokens.
+func HashLimiterID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
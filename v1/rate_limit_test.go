@@ -1,8 +1,12 @@
 package v1
 
 import (
+	"context"
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/suite"
+	"io"
+	"math"
+	"net/http"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -19,7 +23,9 @@ func TestTokensBucket(t *testing.T) {
 }
 
 func (t *TokensBucketTest) Test_NewTokensBucket() {
-	t.Assert().NotNil(NewTokensBucket(10, time.Hour, time.Hour))
+	bucket := NewTokensBucket(10, time.Hour, time.Hour)
+	t.Assert().NotNil(bucket)
+	t.Assert().NoError(bucket.Close())
 }
 
 func (t *TokensBucketTest) new(
@@ -36,10 +42,11 @@ func (t *TokensBucketTest) new(
 		checkTokenTime:  checkTokenTime,
 		shards:          shards,
 		shardCount:      shardCount,
+		done:            make(chan struct{}),
 		sleep:           sleeper,
 	}
 
-	runtime.SetFinalizer(bucket, destructBucket)
+	t.T().Cleanup(func() { _ = bucket.Close() })
 	return bucket
 }
 
@@ -53,8 +60,9 @@ func (t *TokensBucketTest) Test_Obtain_NoThrottle() {
 }
 
 func (t *TokensBucketTest) Test_Obtain_Sleep() {
-	clock := &fakeSleeper{}
+	clock := &fakeSleeper{start: time.Now()}
 	tb := t.new(100, time.Hour, time.Minute, clock)
+	tb.now = clock.Now
 	_, exists := tb.getShard("w").tokens["w"]
 	t.Require().False(exists)
 
@@ -85,10 +93,321 @@ func (t *TokensBucketTest) Test_Obtain_AddRPS() {
 	t.Assert().Equal(2, int(item.rps))
 }
 
+func (t *TokensBucketTest) Test_Allow() {
+	tb := t.new(2, time.Hour, time.Minute, &fakeSleeper{})
+	t.Assert().True(tb.Allow("a"))
+	t.Assert().True(tb.Allow("a"))
+	t.Assert().False(tb.Allow("a"))
+	t.Assert().True(tb.Allow("b"))
+}
+
+func (t *TokensBucketTest) Test_Reserve() {
+	clock := &fakeSleeper{start: time.Now()}
+	tb := t.new(1, time.Hour, time.Minute, clock)
+	tb.now = clock.Now
+
+	t.Assert().Zero(tb.Reserve("a").Delay())
+	next := tb.Reserve("a")
+	t.Assert().Equal(time.Second, next.Delay())
+	t.Assert().Equal(2*time.Second, tb.Reserve("a").Delay())
+
+	clock.Sleep(time.Second)
+	t.Assert().False(tb.Allow("a"))
+	next.Cancel()
+	t.Assert().False(tb.Allow("a"), "only the slot of the current window can be returned")
+}
+
+func (t *TokensBucketTest) Test_Reserve_Cancel() {
+	clock := &fakeSleeper{start: time.Now()}
+	tb := t.new(1, time.Hour, time.Minute, clock)
+	tb.now = clock.Now
+
+	tb.Reserve("a").Cancel()
+	t.Assert().True(tb.Allow("a"))
+	t.Assert().False(tb.Allow("a"))
+}
+
+func (t *TokensBucketTest) Test_Wait() {
+	tb := t.new(1, time.Hour, time.Minute, &fakeSleeper{})
+	t.Require().NoError(tb.Wait(context.Background(), "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	t.Assert().ErrorIs(tb.Wait(ctx, "a"), context.DeadlineExceeded)
+	t.Assert().Less(time.Since(start), 500*time.Millisecond)
+
+	item := tb.getShard("a").tokens["a"]
+	t.Assert().Equal(0, int(item.rps), "cancelled wait should return its slot")
+	t.Assert().False(tb.Allow("a"))
+}
+
+func (t *TokensBucketTest) Test_Wait_Cancelled() {
+	tb := t.new(1, time.Hour, time.Minute, &fakeSleeper{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Assert().ErrorIs(tb.Wait(ctx, "a"), context.Canceled)
+	_, exists := tb.getShard("a").tokens["a"]
+	t.Assert().False(exists)
+}
+
+func (t *TokensBucketTest) Test_NoopLimiter() {
+	limiter := NoopLimiter.(ContextLimiter)
+	t.Assert().True(limiter.Allow("a"))
+	t.Assert().Zero(limiter.Reserve("a").Delay())
+	t.Assert().NoError(limiter.Wait(context.Background(), "a"))
+}
+
+func (t *TokensBucketTest) Test_Stats() {
+	clock := &fakeSleeper{start: time.Now()}
+	tb := t.new(2, time.Hour, time.Minute, clock)
+	tb.now = clock.Now
+
+	var throttled []time.Duration
+	tb.onThrottle = func(id string, wait time.Duration) {
+		t.Assert().Equal(HashLimiterID("a"), id)
+		throttled = append(throttled, wait)
+	}
+
+	tb.Obtain("a")
+	tb.Obtain("b")
+	tb.Obtain("a")
+	clock.Sleep(250 * time.Millisecond)
+	tb.Obtain("a")
+	tb.Obtain("a")
+	tb.Obtain("a")
+
+	stats := tb.Stats()
+	t.Assert().Equal(uint64(2), stats.Waits)
+	t.Assert().Equal([]time.Duration{750 * time.Millisecond, time.Second}, throttled)
+	t.Assert().Equal(1750*time.Millisecond, stats.TotalWait)
+	t.Assert().Equal(time.Second, stats.MaxWait)
+	t.Assert().Len(stats.WaitHistogram, len(waitBuckets)+1)
+	for _, bucket := range stats.WaitHistogram {
+		if bucket.UpperBound == time.Second {
+			t.Assert().Equal(uint64(2), bucket.Count)
+		} else {
+			t.Assert().Zero(bucket.Count, bucket.UpperBound)
+		}
+	}
+
+	total := 0
+	for _, n := range stats.ShardTokens {
+		total += n
+	}
+	t.Assert().Equal(2, total)
+	t.Assert().Len(stats.ShardTokens, len(tb.shards))
+	t.Assert().NotContains(stats.Tokens, "a", "tokens should not be exposed")
+	a, b := stats.Tokens[HashLimiterID("a")], stats.Tokens[HashLimiterID("b")]
+	t.Assert().Equal(uint32(1), b.Requests)
+	t.Assert().Equal(uint32(2), b.Limit)
+	t.Assert().True(clock.start.Equal(b.WindowStart))
+	t.Assert().Equal(uint32(1), a.Requests)
+	t.Assert().True(clock.start.Add(2 * time.Second).Equal(a.WindowStart))
+}
+
+func (t *TokensBucketTest) Test_WaitHistogram() {
+	var stats waitStats
+	for _, wait := range []time.Duration{
+		time.Millisecond, 10 * time.Millisecond, 11 * time.Millisecond, 300 * time.Millisecond, time.Minute,
+	} {
+		stats.record(wait)
+	}
+
+	histogram := stats.histogram()
+	t.Require().Len(histogram, 10)
+	t.Assert().Equal(WaitBucket{UpperBound: 10 * time.Millisecond, Count: 2}, histogram[0])
+	t.Assert().Equal(WaitBucket{UpperBound: 50 * time.Millisecond, Count: 1}, histogram[1])
+	t.Assert().Equal(WaitBucket{UpperBound: 100 * time.Millisecond}, histogram[2])
+	t.Assert().Equal(WaitBucket{UpperBound: 500 * time.Millisecond, Count: 1}, histogram[4])
+	t.Assert().Equal(WaitBucket{UpperBound: math.MaxInt64, Count: 1}, histogram[9])
+
+	var total uint64
+	for _, bucket := range histogram {
+		total += bucket.Count
+	}
+	t.Assert().Equal(stats.waits.Load(), total)
+}
+
+func (t *TokensBucketTest) Test_WithThrottleCallback() {
+	var calls atomic.Int32
+	tb := NewTokensBucket(1, time.Hour, time.Hour, WithThrottleCallback(func(string, time.Duration) {
+		calls.Add(1)
+	}))
+	defer tb.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	t.Require().True(tb.Allow("a"))
+	t.Assert().Error(tb.Wait(ctx, "a"))
+	t.Assert().Zero(calls.Load())
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	t.Assert().Error(tb.Wait(ctx, "a"))
+	t.Assert().Equal(int32(1), calls.Load())
+	t.Assert().Equal(uint64(1), tb.Stats().Waits)
+}
+
+func (t *TokensBucketTest) Test_Close_StopsCleanup() {
+	tb := NewTokensBucket(10, time.Hour, time.Millisecond)
+	gcra := NewGCRALimiter(10, 1, time.Hour, time.Millisecond)
+	adaptive := NewAdaptiveLimiter(AdaptiveLimiterConfig{CheckTokenTime: time.Millisecond})
+
+	for _, limiter := range []io.Closer{tb, gcra, adaptive} {
+		t.Require().NoError(limiter.Close())
+		t.Require().NoError(limiter.Close(), "Close should be idempotent")
+	}
+
+	t.Assert().True(isClosed(tb.done))
+	t.Assert().True(isClosed(gcra.done))
+	t.Assert().True(isClosed(adaptive.done))
+	tb.Obtain("a")
+}
+
+func (t *TokensBucketTest) Test_Close_NoGoroutineLeak() {
+	before := runtime.NumGoroutine()
+	limiters := []io.Closer{
+		NewTokensBucket(10, time.Hour, time.Millisecond),
+		NewGCRALimiter(10, 1, time.Hour, time.Millisecond),
+		NewAdaptiveLimiter(AdaptiveLimiterConfig{CheckTokenTime: time.Millisecond}),
+	}
+	t.Require().GreaterOrEqual(runtime.NumGoroutine(), before+len(limiters))
+
+	for _, limiter := range limiters {
+		t.Require().NoError(limiter.Close())
+	}
+	// Eventually is not used here because it runs the condition in its own goroutine.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	t.Assert().LessOrEqual(runtime.NumGoroutine(), before, "cleanup goroutines should exit after Close")
+}
+
+func (t *TokensBucketTest) Test_RunCleanup_StopsOnDone() {
+	var calls atomic.Int32
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		runCleanup(time.Millisecond, done, func(int64) { calls.Add(1) })
+	}()
+
+	t.Require().Eventually(func() bool { return calls.Load() > 0 }, time.Second, time.Millisecond)
+	close(done)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.FailNow("cleanup should stop after done is closed")
+	}
+}
+
+func (t *TokensBucketTest) Test_LazyCleanup() {
+	tb := NewTokensBucket(10, time.Minute, time.Second, WithLazyCleanup())
+	t.Assert().Nil(tb.done, "lazy cleanup should not start the cleanup goroutine")
+
+	clock := &fakeSleeper{start: time.Now()}
+	tb.now = clock.Now
+	shard := tb.getShard("a")
+	tb.Obtain("a")
+	shard.tokens["stale"] = &token{rps: 1, lastUse: clock.Now().UnixNano()}
+
+	clock.Sleep(30 * time.Second)
+	tb.Obtain("a")
+	t.Assert().Len(shard.tokens, 2)
+
+	clock.Sleep(45 * time.Second)
+	tb.Obtain("a")
+	t.Assert().Len(shard.tokens, 1)
+	t.Assert().Contains(shard.tokens, "a")
+	t.Assert().NoError(tb.Close())
+}
+
+func (t *TokensBucketTest) Test_GCRALazyCleanup() {
+	limiter := NewGCRALimiter(10, 1, time.Minute, time.Second, WithGCRALazyCleanup())
+	t.Assert().Nil(limiter.done, "lazy cleanup should not start the cleanup goroutine")
+
+	clock := &fakeSleeper{start: time.Now()}
+	limiter.now = clock.Now
+	shard := limiter.getShard("a")
+	limiter.Obtain("a")
+	shard.tats["stale"] = clock.Now().UnixNano()
+
+	clock.Sleep(30 * time.Second)
+	limiter.Obtain("a")
+	t.Assert().Len(shard.tats, 2)
+
+	clock.Sleep(45 * time.Second)
+	limiter.Obtain("a")
+	t.Assert().Len(shard.tats, 1)
+	t.Assert().Contains(shard.tats, "a")
+	t.Assert().NoError(limiter.Close())
+}
+
+func (t *TokensBucketTest) Test_AdaptiveLazyCleanup() {
+	limiter := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+		UnusedTokenTime: time.Minute,
+		CheckTokenTime:  time.Second,
+		LazyCleanup:     true,
+	})
+	t.Assert().Nil(limiter.done, "lazy cleanup should not start the cleanup goroutine")
+
+	clock := &fakeSleeper{start: time.Now()}
+	limiter.now = clock.Now
+	shard := limiter.getShard("a")
+	limiter.Obtain("a")
+	shard.tokens["stale"] = &adaptiveToken{tat: clock.Now().UnixNano(), rate: 1}
+
+	clock.Sleep(30 * time.Second)
+	limiter.Obtain("a")
+	t.Assert().Len(shard.tokens, 2)
+
+	clock.Sleep(45 * time.Second)
+	limiter.ObserveResponse("a", http.StatusOK, nil)
+	t.Assert().Len(shard.tokens, 1)
+	t.Assert().Contains(shard.tokens, "a")
+	t.Assert().NoError(limiter.Close())
+}
+
+func (t *TokensBucketTest) Test_ClientClosesOwnedLimiter() {
+	shared := NewTokensBucket(10, time.Hour, time.Hour)
+	c := New("https://mg.example.com", "token").WithLimiter(shared)
+	var closer io.Closer = c
+	t.Require().NoError(closer.Close())
+	t.Assert().False(isClosed(shared.done), "shared limiter should not be closed by the client")
+
+	owned := NewTokensBucket(10, time.Hour, time.Hour)
+	t.Require().NoError(New("https://mg.example.com", "token").WithOwnedLimiter(owned).Close())
+	t.Assert().True(isClosed(owned.done))
+	t.Require().NoError(New("https://mg.example.com", "token").WithOwnedLimiter(NoopLimiter).Close())
+
+	t.Require().NoError(shared.Close())
+	t.Assert().True(isClosed(shared.done))
+}
+
+// isClosed returns true if the done channel of the limiter is closed.
+func isClosed(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
 type fakeSleeper struct {
-	total atomic.Uint32
+	total   atomic.Uint32
+	start   time.Time
+	elapsed atomic.Int64
 }
 
-func (s *fakeSleeper) Sleep(time.Duration) {
+func (s *fakeSleeper) Sleep(d time.Duration) {
 	s.total.Add(1)
+	s.elapsed.Add(int64(d))
+}
+
+func (s *fakeSleeper) Now() time.Time {
+	return s.start.Add(time.Duration(s.elapsed.Load()))
 }
@@ -2,10 +2,13 @@ package v1
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"slices"
+	"time"
 )
 
 const MaxRPS = 100
@@ -13,40 +16,76 @@ const MaxRPS = 100
 var prefix = "/api/transport/v1"
 
 // GetRequest performs GET request to the provided route.
+//
+// Responses with 4xx and 5xx statuses are returned with *HTTPClientError, the body and the status are still
+// returned along with it. Before, the error was returned only for 5xx statuses and network failures.
 func (c *MgClient) GetRequest(url string, parameters []byte) ([]byte, int, error) {
+	return c.GetRequestContext(context.Background(), url, parameters)
+}
+
+// GetRequestContext performs GET request to the provided route using the provided context.
+func (c *MgClient) GetRequestContext(ctx context.Context, url string, parameters []byte) ([]byte, int, error) {
 	return makeRequest(
+		ctx,
 		"GET",
-		fmt.Sprintf("%s%s%s", c.URL, prefix, url),
+		url,
 		bytes.NewBuffer(parameters),
 		c,
 	)
 }
 
 // PostRequest performs POST request to the provided route.
+//
+// Responses with 4xx and 5xx statuses are returned with *HTTPClientError, the body and the status are still
+// returned along with it. Before, the error was returned only for 5xx statuses and network failures.
 func (c *MgClient) PostRequest(url string, parameters io.Reader) ([]byte, int, error) {
+	return c.PostRequestContext(context.Background(), url, parameters)
+}
+
+// PostRequestContext performs POST request to the provided route using the provided context.
+func (c *MgClient) PostRequestContext(ctx context.Context, url string, parameters io.Reader) ([]byte, int, error) {
 	return makeRequest(
+		ctx,
 		"POST",
-		fmt.Sprintf("%s%s%s", c.URL, prefix, url),
+		url,
 		parameters,
 		c,
 	)
 }
 
 // PutRequest performs PUT request to the provided route.
+//
+// Responses with 4xx and 5xx statuses are returned with *HTTPClientError, the body and the status are still
+// returned along with it. Before, the error was returned only for 5xx statuses and network failures.
 func (c *MgClient) PutRequest(url string, parameters []byte) ([]byte, int, error) {
+	return c.PutRequestContext(context.Background(), url, parameters)
+}
+
+// PutRequestContext performs PUT request to the provided route using the provided context.
+func (c *MgClient) PutRequestContext(ctx context.Context, url string, parameters []byte) ([]byte, int, error) {
 	return makeRequest(
+		ctx,
 		"PUT",
-		fmt.Sprintf("%s%s%s", c.URL, prefix, url),
+		url,
 		bytes.NewBuffer(parameters),
 		c,
 	)
 }
 
 // DeleteRequest performs DELETE request to the provided route.
+//
+// Responses with 4xx and 5xx statuses are returned with *HTTPClientError, the body and the status are still
+// returned along with it. Before, the error was returned only for 5xx statuses and network failures.
 func (c *MgClient) DeleteRequest(url string, parameters []byte) ([]byte, int, error) {
+	return c.DeleteRequestContext(context.Background(), url, parameters)
+}
+
+// DeleteRequestContext performs DELETE request to the provided route using the provided context.
+func (c *MgClient) DeleteRequestContext(ctx context.Context, url string, parameters []byte) ([]byte, int, error) {
 	return makeRequest(
+		ctx,
 		"DELETE",
-		fmt.Sprintf("%s%s%s", c.URL, prefix, url),
+		url,
 		bytes.NewBuffer(parameters),
 		c,
 	)
@@ -58,56 +97,263 @@ func (c *MgClient) WaitForRateLimit() {
 	}
 }
 
-func makeRequest(reqType, url string, buf io.Reader, c *MgClient) ([]byte, int, error) {
-	var res []byte
-	req, err := http.NewRequest(reqType, url, buf)
+// WaitForRateLimitContext works like WaitForRateLimit but returns early with the context error
+// if the context is done before the limiter allows the request. Limiters implementing ContextLimiter
+// are waited on directly, other limiters are waited on in a separate goroutine.
+func (c *MgClient) WaitForRateLimitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if c.limiter == nil || c.Token == "" {
+		return nil
+	}
+
+	return waitLimiter(ctx, c.limiter, c.Token)
+}
+
+// waitForRouteRateLimit waits for the limiter of the endpoint group the request route belongs to.
+func (c *MgClient) waitForRouteRateLimit(ctx context.Context, req *APIRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	limiter, key := c.routeLimiter(req.Method, req.Route)
+	if limiter == nil {
+		return nil
+	}
+
+	start := time.Now()
+	err := waitLimiter(ctx, limiter, key)
+	c.metricsOrDefault().LimiterWait(req.Method, req.Route, time.Since(start))
+	return err
+}
+
+// routeLimiter returns the limiter and the limiter key for the route. Endpoint groups configured
+// in the GroupLimiter are limited using the token and the group name as the key.
+func (c *MgClient) routeLimiter(method, route string) (Limiter, string) {
+	if c.limiter == nil || c.Token == "" {
+		return nil, ""
+	}
+
+	if grouped, ok := c.limiter.(GroupLimiter); ok {
+		group := RouteEndpointGroup(route)
+		if limiter, ok := grouped.Group(group); ok {
+			return limiter, c.Token + ":" + string(group)
+		}
+	}
+
+	return c.limiter, c.Token
+}
+
+func waitLimiter(ctx context.Context, limiter Limiter, id string) error {
+	if limiter, ok := limiter.(ContextLimiter); ok {
+		return limiter.Wait(ctx, id)
+	}
+
+	if ctx.Done() == nil {
+		limiter.Obtain(id)
+		return nil
+	}
+
+	obtained := make(chan struct{})
+	go func() {
+		limiter.Obtain(id)
+		close(obtained)
+	}()
+
+	select {
+	case <-obtained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// makeRequest performs the request. Responses with 4xx and 5xx statuses are returned along with *HTTPClientError.
+func makeRequest(ctx context.Context, reqType, path string, buf io.Reader, c *MgClient) ([]byte, int, error) {
+	resp, err := c.do(ctx, reqType, path, buf)
+	if resp == nil {
+		return nil, 0, err
+	}
+
+	return resp.Body, resp.StatusCode, err
+}
+
+// do performs the request. The response is returned for every received reply, even if it's unsuccessful.
+// Responses with 4xx and 5xx statuses are returned along with *HTTPClientError.
+func (c *MgClient) do(ctx context.Context, method, path string, buf io.Reader) (*APIResponse, error) {
+	body, err := newRequestBody(buf)
 	if err != nil {
-		return res, 0, err
+		return nil, err
 	}
 
+	req := &APIRequest{
+		Method: method,
+		Route:  routeTemplate(path),
+		Path:   path,
+		URL:    fmt.Sprintf("%s%s%s", c.URL, prefix, path),
+		Header: http.Header{},
+		body:   body,
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Transport-Token", c.Token)
 
-	maxAttempt := 1
-	if c.limiter != nil && c.Token != "" {
-		maxAttempt = 3
+	ctx, span := c.startRequestSpan(ctx, req)
+	start := time.Now()
+	resp, err := c.sendWithRetries(ctx, req)
+	if err != nil {
+		err = NewCriticalHTTPError(err)
+		c.finishRequest(span, req, resp, err, time.Since(start))
+		return nil, err
 	}
 
-	attempt := 0
-tryAgain:
-	c.WaitForRateLimit()
-	if c.Debug {
-		if strings.Contains(url, "/files/upload") {
-			c.writeLog("MG TRANSPORT API Request: %s %s %s [file data]", reqType, url, c.Token)
-		} else {
-			c.writeLog("MG TRANSPORT API Request: %s %s %s %v", reqType, url, c.Token, buf)
-		}
+	resp.request = req
+	if c.Debug && c.structuredLogger() == nil {
+		c.writeLog("MG TRANSPORT API Response: %s", c.debugLogger().body(resp.Body))
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		err := newAPIError(req, resp)
+		c.finishRequest(span, req, resp, err, time.Since(start))
+		return resp, err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	c.finishRequest(span, req, resp, nil, time.Since(start))
+	return resp, nil
+}
+
+// finishRequest reports the finished API call to the metrics and ends its span.
+func (c *MgClient) finishRequest(span Span, req *APIRequest, resp *APIResponse, err error, duration time.Duration) {
+	c.recordRequest(req, resp, err, duration)
+	endRequestSpan(span, req, resp, err)
+}
+
+// decodeResponse checks the response status and decodes the response body into the T value.
+// The response is considered successful only if its status is one of the provided success statuses.
+func decodeResponse[T any](resp *APIResponse, err error, success ...int) (T, int, error) {
+	var result T
+
+	status, err := checkResponse(resp, err, success...)
 	if err != nil {
-		return res, 0, NewCriticalHTTPError(err)
+		return result, status, err
 	}
 
-	if resp.StatusCode == http.StatusTooManyRequests && attempt < maxAttempt {
-		attempt++
-		c.writeLog("MG TRANSPORT API Request rate limit hit on attempt %d, retrying", attempt)
-		goto tryAgain
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return result, status, err
 	}
 
-	if resp.StatusCode >= http.StatusInternalServerError {
-		err = NewServerError(resp)
-		return res, resp.StatusCode, err
+	return result, status, nil
+}
+
+// checkResponse checks that the request was successful and the response status is one of the success statuses.
+func checkResponse(resp *APIResponse, err error, success ...int) (int, error) {
+	if resp == nil {
+		return 0, err
 	}
 
-	res, err = buildLimitedRawResponse(resp)
 	if err != nil {
-		return res, 0, err
+		return resp.StatusCode, err
+	}
+
+	if !slices.Contains(success, resp.StatusCode) {
+		return resp.StatusCode, newAPIError(resp.request, resp)
+	}
+
+	if resp.Truncated {
+		return resp.StatusCode, ErrResponseTruncated
+	}
+
+	return resp.StatusCode, nil
+}
+
+// sendWithRetries sends the request through the middleware chain until it succeeds or the retry policy gives up.
+func (c *MgClient) sendWithRetries(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+	policy := c.retryPolicyOrDefault()
+	doer := c.doer()
+
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForRouteRateLimit(ctx, req); err != nil {
+			return nil, err
+		}
+
+		c.logRequest(req)
+		req.Attempt = attempt
+		started := time.Now()
+		resp, err := doer.Do(ctx, req)
+		if logger := c.structuredLogger(); logger != nil {
+			logger.logAttempt(ctx, c.Token, req, resp, err, time.Since(started))
+		}
+
+		c.observeResponse(req, resp)
+		countRateLimited(req, resp)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		info := RetryAttempt{Number: attempt, Method: req.Method, Route: req.Route, Err: err}
+		if resp != nil {
+			info.StatusCode = resp.StatusCode
+			info.Header = resp.Header
+		}
+
+		delay, retry := policy.NextRetry(info)
+		if !retry {
+			return resp, err
+		}
+
+		c.logRetry(ctx, req, resp, err, delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// logRequest writes the request to the debug log. The debug log is not used if the structured logger is set.
+// The body is redacted and cut the same way as the bodies logged by WithSlog.
+func (c *MgClient) logRequest(req *APIRequest) {
+	if !c.Debug || c.structuredLogger() != nil {
+		return
+	}
+
+	c.writeLog("MG TRANSPORT API Request: %s %s %s %s",
+		req.Method, req.URL, maskToken(c.Token), c.debugLogger().requestBody(req))
+}
+
+// logRetry writes the retry of the failed attempt to the log.
+func (c *MgClient) logRetry(ctx context.Context, req *APIRequest, resp *APIResponse, err error, delay time.Duration) {
+	logger := c.structuredLogger()
+	switch {
+	case logger != nil:
+		logger.logRetry(ctx, c.Token, req, delay)
+	case resp != nil:
+		c.writeLog("MG TRANSPORT API Request failed with status %d on attempt %d, retrying in %s",
+			resp.StatusCode, req.Attempt, delay)
+	default:
+		c.writeLog("MG TRANSPORT API Request failed on attempt %d: %s, retrying in %s", req.Attempt, err, delay)
+	}
+}
+
+// observeResponse passes the response to the limiter if it adjusts its rate using the responses.
+func (c *MgClient) observeResponse(req *APIRequest, resp *APIResponse) {
+	if resp == nil {
+		return
 	}
 
-	if c.Debug {
-		c.writeLog("MG TRANSPORT API Response: %s", res)
+	limiter, key := c.routeLimiter(req.Method, req.Route)
+	if observer, ok := limiter.(ResponseObserver); ok {
+		observer.ObserveResponse(key, resp.StatusCode, resp.Header)
+	}
+}
+
+func (c *MgClient) retryPolicyOrDefault() RetryPolicy {
+	if c.retryPolicy != nil {
+		return c.retryPolicy
+	}
+
+	if c.limiter != nil && c.Token != "" {
+		return &limiterRetryPolicy{}
 	}
 
-	return res, resp.StatusCode, err
+	return NoRetryPolicy
 }
@@ -0,0 +1,236 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+type recordingServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	bodies    []string
+	failFirst int32
+	requests  atomic.Int32
+}
+
+func newRecordingServer(failFirst int32, status int) *recordingServer {
+	srv := &recordingServer{failFirst: failFirst}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		srv.mu.Lock()
+		srv.bodies = append(srv.bodies, string(body))
+		srv.mu.Unlock()
+
+		if srv.requests.Add(1) <= srv.failFirst {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"errors":["try again"]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"message_id":1,"id":"file_id"}`))
+	}))
+
+	return srv
+}
+
+func retryingClient(url string) *MgClient {
+	policy := NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	return New(url, "token").WithRetryPolicy(policy)
+}
+
+func TestMakeRequest_RetryResendsBody(t *testing.T) {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	resp, status, err := retryingClient(srv.URL).Messages(SendData{
+		Message:        Message{ExternalID: "1", Type: MsgTypeText, Text: "hello"},
+		Channel:        1,
+		ExternalChatID: "chat",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, resp.MessageID)
+
+	require.Len(t, srv.bodies, 2)
+	assert.Contains(t, srv.bodies[0], `"text":"hello"`)
+	assert.Equal(t, srv.bodies[0], srv.bodies[1])
+}
+
+func TestMakeRequest_RetryResendsUploadedFile(t *testing.T) {
+	srv := newRecordingServer(2, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	data := strings.Repeat("file contents ", 1024)
+	_, status, err := retryingClient(srv.URL).UploadFile(io.MultiReader(strings.NewReader(data)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	require.Len(t, srv.bodies, 3)
+	for _, body := range srv.bodies {
+		assert.Equal(t, data, body)
+	}
+}
+
+func TestMakeRequest_RetryRewindsSeekableFile(t *testing.T) {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	file, err := os.CreateTemp(t.TempDir(), "upload")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString("header|file data")
+	require.NoError(t, err)
+	_, err = file.Seek(int64(len("header|")), io.SeekStart)
+	require.NoError(t, err)
+
+	_, _, err = retryingClient(srv.URL).UploadFile(file)
+	require.NoError(t, err)
+	require.Len(t, srv.bodies, 2)
+	assert.Equal(t, "file data", srv.bodies[0])
+	assert.Equal(t, "file data", srv.bodies[1])
+}
+
+func TestMakeRequest_ClosesFailedResponses(t *testing.T) {
+	transport := &closeTrackingTransport{}
+	policy := NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	c := NewWithClient("https://mg.example.com", "token", &http.Client{Transport: transport}).
+		WithRetryPolicy(policy)
+
+	_, status, err := c.TransportChannels(Channels{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int32(3), transport.requests.Load())
+	assert.Equal(t, int32(3), transport.closed.Load())
+}
+
+func TestRequestBody(t *testing.T) {
+	body, err := newRequestBody(bytes.NewBufferString("buffer"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), body.size())
+	assert.Equal(t, "buffer", body.String())
+
+	for i := 0; i < 2; i++ {
+		r, err := body.reader()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		assert.Equal(t, "buffer", string(data))
+	}
+
+	body, err = newRequestBody(nil)
+	require.NoError(t, err)
+	r, err := body.reader()
+	require.NoError(t, err)
+	assert.Equal(t, http.NoBody, r)
+}
+
+type closeTrackingTransport struct {
+	requests atomic.Int32
+	closed   atomic.Int32
+}
+
+func (t *closeTrackingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	if t.requests.Add(1) < 3 {
+		status = http.StatusServiceUnavailable
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       &closeTrackingBody{Reader: strings.NewReader("[]"), closed: &t.closed},
+	}, nil
+}
+
+type closeTrackingBody struct {
+	io.Reader
+	closed *atomic.Int32
+}
+
+func (b *closeTrackingBody) Close() error {
+	b.closed.Add(1)
+	return nil
+}
+
+func TestDecodeResponse_ChecksStatusFirst(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://mg-test.retailcrm.pro").
+		Get("/api/transport/v1/templates").
+		Reply(http.StatusForbidden).
+		BodyString("<html><body>Forbidden</body></html>")
+
+	_, status, err := New("https://mg-test.retailcrm.pro", "mg_token").TransportTemplates()
+	assert.Equal(t, http.StatusForbidden, status)
+
+	clientErr := AsClientError(err)
+	require.NotNil(t, clientErr)
+	assert.True(t, clientErr.IsAuth())
+	assert.Equal(t, "/templates", clientErr.Route)
+	assert.Equal(t, "<html><body>Forbidden</body></html>", string(clientErr.Body))
+}
+
+func TestDecodeResponse_UnexpectedSuccessStatus(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://mg-test.retailcrm.pro").
+		Post("/api/transport/v1/messages").
+		Reply(http.StatusCreated).
+		JSON(MessagesResponse{MessageID: 1})
+
+	_, status, err := New("https://mg-test.retailcrm.pro", "mg_token").Messages(SendData{})
+	assert.Equal(t, http.StatusCreated, status)
+
+	clientErr := AsClientError(err)
+	require.NotNil(t, clientErr)
+	assert.Equal(t, http.StatusCreated, clientErr.StatusCode)
+	assert.Equal(t, "/messages", clientErr.Route)
+}
+
+func TestDecodeResponse_InvalidJSON(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://mg-test.retailcrm.pro").
+		Get("/api/transport/v1/files/file").
+		Reply(http.StatusOK).
+		BodyString("{")
+
+	_, status, err := New("https://mg-test.retailcrm.pro", "mg_token").GetFile("file")
+	assert.Equal(t, http.StatusOK, status)
+
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
+
+func TestCheckResponse_Truncated(t *testing.T) {
+	req := &APIRequest{Method: http.MethodGet, Route: "/templates"}
+	resp := &APIResponse{StatusCode: http.StatusOK, Body: []byte("[]"), Truncated: true, request: req}
+
+	status, err := checkResponse(resp, nil, http.StatusOK)
+	assert.Equal(t, http.StatusOK, status)
+	assert.ErrorIs(t, err, ErrResponseTruncated)
+}
+
+func TestReadLimitedResponse(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), LimitResponse+10)
+	body, truncated, err := readLimitedResponse(&http.Response{Body: io.NopCloser(bytes.NewReader(data))})
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, body, LimitResponse)
+
+	body, truncated, err = readLimitedResponse(&http.Response{Body: io.NopCloser(strings.NewReader("{}"))})
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, "{}", string(body))
+}
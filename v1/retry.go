@@ -0,0 +1,213 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 10 * time.Second
+	defaultRetryJitter    = 0.2
+	limiterRetryAttempts  = 4
+)
+
+// DefaultRetryStatuses contains HTTP statuses which are retried by BackoffRetryPolicy by default.
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// NoRetryPolicy never retries failed requests.
+var NoRetryPolicy RetryPolicy = &noRetryPolicy{}
+
+// RetryPolicy decides whether a failed request should be sent again.
+type RetryPolicy interface {
+	// NextRetry is called after every failed attempt. It returns the delay before the next attempt
+	// and false if the request should not be retried.
+	NextRetry(attempt RetryAttempt) (time.Duration, bool)
+}
+
+// RetryAttempt describes the failed request attempt.
+type RetryAttempt struct {
+	// Number of the attempt, starting from 1.
+	Number int
+	// Method of the request.
+	Method string
+	// Route template of the request, e.g. "/channels/{id}".
+	Route string
+	// StatusCode of the response. It will be 0 if request has failed with Err.
+	StatusCode int
+	// Header of the response. It will be nil if request has failed with Err.
+	Header http.Header
+	// Err is a network error if there was one.
+	Err error
+}
+
+// Idempotent returns true if the request can be safely repeated.
+func (a RetryAttempt) Idempotent() bool {
+	return IsIdempotentRoute(a.Method, a.Route)
+}
+
+// BackoffRetryPolicy retries requests with exponential backoff and jitter.
+//
+// Responses with 429 and 503 statuses are always retried because MG did not process such requests.
+// Other retryable statuses and network errors are retried only for idempotent routes unless RetryNonIdempotent
+// is set. The Retry-After header is honored; the request is not retried if the server asks to wait longer
+// than MaxDelay.
+type BackoffRetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the second attempt. It is doubled for every next attempt.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between attempts.
+	MaxDelay time.Duration
+	// Jitter is a fraction of the delay (from 0 to 1) which is randomized.
+	Jitter float64
+	// RetryStatuses contains retryable statuses. DefaultRetryStatuses is used if it's nil.
+	RetryStatuses []int
+	// RetryNonIdempotent enables retries of non-idempotent requests on gateway and network errors.
+	RetryNonIdempotent bool
+	random             func() float64
+}
+
+// NewBackoffRetryPolicy returns BackoffRetryPolicy with provided attempts count and default delays.
+func NewBackoffRetryPolicy(maxAttempts int) *BackoffRetryPolicy {
+	return &BackoffRetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		Jitter:      defaultRetryJitter,
+	}
+}
+
+// NextRetry implements RetryPolicy.
+func (p *BackoffRetryPolicy) NextRetry(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Number >= p.MaxAttempts || !p.retryable(attempt) {
+		return 0, false
+	}
+
+	if delay, ok := RetryAfter(attempt.Header); ok {
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			return 0, false
+		}
+		return delay, true
+	}
+
+	return p.backoff(attempt.Number), true
+}
+
+func (p *BackoffRetryPolicy) retryable(attempt RetryAttempt) bool {
+	if attempt.Err != nil {
+		if errors.Is(attempt.Err, context.Canceled) || errors.Is(attempt.Err, context.DeadlineExceeded) {
+			return false
+		}
+		return p.RetryNonIdempotent || attempt.Idempotent() || isDialError(attempt.Err)
+	}
+
+	statuses := p.RetryStatuses
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+
+	for _, status := range statuses {
+		if status != attempt.StatusCode {
+			continue
+		}
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			return true
+		}
+		return p.RetryNonIdempotent || attempt.Idempotent()
+	}
+
+	return false
+}
+
+func (p *BackoffRetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			delay = p.MaxDelay
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		random := p.random
+		if random == nil {
+			random = rand.Float64 //nolint:gosec
+		}
+		delay -= time.Duration(float64(delay) * p.Jitter * random())
+	}
+
+	return delay
+}
+
+// RetryAfter parses the Retry-After header. It supports both delay in seconds and HTTP date.
+func RetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// limiterRetryPolicy is used by default if the limiter is present. It retries rate-limited requests immediately
+// because the limiter will throttle the next attempt.
+type limiterRetryPolicy struct{}
+
+func (p *limiterRetryPolicy) NextRetry(attempt RetryAttempt) (time.Duration, bool) {
+	return 0, attempt.StatusCode == http.StatusTooManyRequests && attempt.Number < limiterRetryAttempts
+}
+
+type noRetryPolicy struct{}
+
+func (p *noRetryPolicy) NextRetry(RetryAttempt) (time.Duration, bool) {
+	return 0, false
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// sleepContext waits for the provided duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	assert.Equal(t, "/messages", routeTemplate("/messages"))
+	assert.Equal(t, "/channels", routeTemplate("/channels?active=true"))
+	assert.Equal(t, "/channels/{id}", routeTemplate("/channels/1"))
+	assert.Equal(t, "/channels/{id}/templates", routeTemplate("/channels/1/templates"))
+	assert.Equal(t, "/channels/{id}/templates/{code}", routeTemplate("/channels/1/templates/code%23a"))
+	assert.Equal(t, "/files/{id}", routeTemplate("/files/file_id"))
+	assert.Equal(t, "/files/upload", routeTemplate("/files/upload"))
+	assert.Equal(t, "/files/upload_by_url", routeTemplate("/files/upload_by_url"))
+}
+
+func TestIsIdempotentRoute(t *testing.T) {
+	assert.True(t, IsIdempotentRoute(http.MethodGet, "/channels"))
+	assert.True(t, IsIdempotentRoute(http.MethodPut, "/messages"))
+	assert.True(t, IsIdempotentRoute(http.MethodDelete, "/channels/{id}"))
+	assert.True(t, IsIdempotentRoute(http.MethodPost, "/messages/ack"))
+	assert.False(t, IsIdempotentRoute(http.MethodPost, "/messages"))
+	assert.False(t, IsIdempotentRoute(http.MethodPost, "/files/upload"))
+}
+
+func TestBackoffRetryPolicy_NextRetry(t *testing.T) {
+	policy := NewBackoffRetryPolicy(3)
+	policy.Jitter = 0
+
+	delay, retry := policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages", StatusCode: http.StatusTooManyRequests})
+	assert.True(t, retry)
+	assert.Equal(t, defaultRetryBaseDelay, delay)
+
+	delay, retry = policy.NextRetry(RetryAttempt{
+		Number: 2, Method: http.MethodGet, Route: "/channels", StatusCode: http.StatusBadGateway})
+	assert.True(t, retry)
+	assert.Equal(t, 2*defaultRetryBaseDelay, delay)
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 3, Method: http.MethodGet, Route: "/channels", StatusCode: http.StatusBadGateway})
+	assert.False(t, retry, "attempts are exhausted")
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages", StatusCode: http.StatusGatewayTimeout})
+	assert.False(t, retry, "non-idempotent request should not be retried on gateway timeout")
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodGet, Route: "/channels", StatusCode: http.StatusBadRequest})
+	assert.False(t, retry)
+}
+
+func TestBackoffRetryPolicy_NextRetry_Errors(t *testing.T) {
+	policy := NewBackoffRetryPolicy(3)
+
+	_, retry := policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages", Err: errors.New("connection reset by peer")})
+	assert.False(t, retry)
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages",
+		Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}})
+	assert.True(t, retry, "request was not sent, it can be retried")
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodGet, Route: "/channels", Err: errors.New("connection reset by peer")})
+	assert.True(t, retry)
+
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodGet, Route: "/channels", Err: context.Canceled})
+	assert.False(t, retry)
+}
+
+func TestBackoffRetryPolicy_Backoff(t *testing.T) {
+	policy := NewBackoffRetryPolicy(10)
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = 5 * time.Second
+	policy.Jitter = 0.5
+	policy.random = func() float64 { return 1 }
+
+	assert.Equal(t, 500*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, time.Second, policy.backoff(2))
+	assert.Equal(t, 2500*time.Millisecond, policy.backoff(5))
+	assert.Equal(t, 2500*time.Millisecond, policy.backoff(30))
+}
+
+func TestBackoffRetryPolicy_RetryAfter(t *testing.T) {
+	policy := NewBackoffRetryPolicy(3)
+	header := http.Header{}
+	header.Set("Retry-After", "2")
+
+	delay, retry := policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages",
+		StatusCode: http.StatusTooManyRequests, Header: header})
+	assert.True(t, retry)
+	assert.Equal(t, 2*time.Second, delay)
+
+	header.Set("Retry-After", "3600")
+	_, retry = policy.NextRetry(RetryAttempt{
+		Number: 1, Method: http.MethodPost, Route: "/messages",
+		StatusCode: http.StatusTooManyRequests, Header: header})
+	assert.False(t, retry, "server asks to wait longer than MaxDelay")
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	_, ok := RetryAfter(header)
+	assert.False(t, ok)
+
+	header.Set("Retry-After", "5")
+	delay, ok := RetryAfter(header)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, delay)
+
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	delay, ok = RetryAfter(header)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour, delay, float64(time.Minute))
+
+	header.Set("Retry-After", "soon")
+	_, ok = RetryAfter(header)
+	assert.False(t, ok)
+}
+
+func TestLimiterRetryPolicy_NextRetry(t *testing.T) {
+	policy := &limiterRetryPolicy{}
+	_, retry := policy.NextRetry(RetryAttempt{Number: 1, StatusCode: http.StatusTooManyRequests})
+	assert.True(t, retry)
+	_, retry = policy.NextRetry(RetryAttempt{Number: 4, StatusCode: http.StatusTooManyRequests})
+	assert.False(t, retry)
+	_, retry = policy.NextRetry(RetryAttempt{Number: 1, StatusCode: http.StatusBadGateway})
+	assert.False(t, retry)
+}
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+)
+
+// idempotentPostRoutes contains POST routes which can be safely sent more than once.
+var idempotentPostRoutes = map[string]struct{}{
+	"/messages/ack":        {},
+	"/messages/read":       {},
+	"/messages/read_until": {},
+	"/messages/reaction":   {},
+	"/messages/restore":    {},
+}
+
+// EndpointGroup is a group of API endpoints which can be rate limited separately.
+type EndpointGroup string
+
+const (
+	// EndpointMessages contains message sending, editing, deletion, reactions and read events.
+	EndpointMessages EndpointGroup = "messages"
+	// EndpointHistory contains the messages history import.
+	EndpointHistory EndpointGroup = "history"
+	// EndpointFiles contains the file uploads and downloads.
+	EndpointFiles EndpointGroup = "files"
+	// EndpointChannels contains the channels management.
+	EndpointChannels EndpointGroup = "channels"
+	// EndpointTemplates contains the templates management.
+	EndpointTemplates EndpointGroup = "templates"
+)
+
+// routeTemplate returns the route template for the provided path. Identifiers inside the path are replaced
+// with placeholders and the query string is removed: "/channels/1/templates/code?a=b" becomes
+// "/channels/{id}/templates/{code}".
+func routeTemplate(path string) string {
+	if idx := strings.IndexByte(path, '?'); idx != -1 {
+		path = path[:idx]
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "channels":
+		segments[1] = "{id}"
+		if len(segments) >= 4 && segments[2] == "templates" {
+			segments[3] = "{code}"
+		}
+	case len(segments) == 2 && segments[0] == "files" &&
+		segments[1] != "upload" && segments[1] != "upload_by_url":
+		segments[1] = "{id}"
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// IsIdempotentRoute returns true if repeating the request to the route has no additional effect on MG.
+// The route should be a route template as used in the RetryAttempt.
+func IsIdempotentRoute(method, route string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		_, ok := idempotentPostRoutes[route]
+		return ok
+	default:
+		return false
+	}
+}
+
+// RouteEndpointGroup returns the endpoint group of the route. The route should be a route template
+// as used in the RetryAttempt. Unknown routes belong to the EndpointMessages group.
+func RouteEndpointGroup(route string) EndpointGroup {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	switch {
+	case segments[0] == "messages" && len(segments) > 1 && segments[1] == "history":
+		return EndpointHistory
+	case segments[0] == "files":
+		return EndpointFiles
+	case segments[0] == "templates" || (len(segments) > 2 && segments[2] == "templates"):
+		return EndpointTemplates
+	case segments[0] == "channels":
+		return EndpointChannels
+	default:
+		return EndpointMessages
+	}
+}
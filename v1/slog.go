@@ -0,0 +1,245 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxLogBodySize is the default limit of the logged request and response bodies in bytes.
+const DefaultMaxLogBodySize = 4096
+
+const (
+	redactedValue   = "[REDACTED]"
+	maskedTokenSize = 4
+)
+
+// DefaultRedactFields are the JSON fields which values are redacted everywhere in the logged bodies.
+var DefaultRedactFields = []string{"phone", "email"}
+
+// customerRedactFields are the JSON fields which values are redacted inside the "customer" objects.
+var customerRedactFields = map[string]struct{}{
+	"first_name": {},
+	"last_name":  {},
+	"nickname":   {},
+}
+
+// SlogConfig configures the structured logging set by WithSlog.
+type SlogConfig struct {
+	// LogBodies enables logging of the request and response bodies. Customer names and the RedactFields
+	// are redacted from the JSON bodies, file uploads and non-JSON bodies are not logged.
+	LogBodies bool
+	// MaxBodySize is the limit of the logged body in bytes, longer bodies are cut. DefaultMaxLogBodySize is used
+	// if it's zero.
+	MaxBodySize int
+	// RedactFields are the JSON fields which values are redacted everywhere in the logged bodies.
+	// DefaultRedactFields are used if it's nil.
+	RedactFields []string
+}
+
+// slogLogger writes the structured client logs.
+type slogLogger struct {
+	logger      *slog.Logger
+	level       slog.Level // level of the successful attempts
+	logBodies   bool
+	maxBodySize int
+	redact      map[string]struct{}
+}
+
+// WithSlog sets the structured logger into the Client. It replaces the Debug output and the BasicLogger: every
+// attempt is logged with the method, route, status, duration and attempt number, successful attempts are logged
+// with slog.LevelDebug, failed ones with slog.LevelWarn. The transport token is always masked.
+func (c *MgClient) WithSlog(logger *slog.Logger, config SlogConfig) *MgClient {
+	if logger == nil {
+		c.slog = nil
+		return c
+	}
+
+	c.slog = newSlogLogger(logger, config)
+	return c
+}
+
+// newSlogLogger creates the slogLogger which logs the successful attempts with slog.LevelDebug.
+func newSlogLogger(logger *slog.Logger, config SlogConfig) *slogLogger {
+	fields := config.RedactFields
+	if fields == nil {
+		fields = DefaultRedactFields
+	}
+
+	redact := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		redact[field] = struct{}{}
+	}
+
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxLogBodySize
+	}
+
+	return &slogLogger{
+		logger:      logger,
+		level:       slog.LevelDebug,
+		logBodies:   config.LogBodies,
+		maxBodySize: maxBodySize,
+		redact:      redact,
+	}
+}
+
+// structuredLogger returns the logger set by WithSlog. In the Debug mode without the BasicLogger it returns
+// the logger based on slog.Default() which logs every attempt with its bodies at the info level.
+func (c *MgClient) structuredLogger() *slogLogger {
+	if c.slog != nil || !c.Debug || c.logger != nil {
+		return c.slog
+	}
+
+	return c.debugLogger()
+}
+
+// debugLogger returns the logger based on slog.Default() which is used in the Debug mode. It's also used to redact
+// the bodies written to the BasicLogger. The logger is created once and recreated only if slog.Default() changes.
+func (c *MgClient) debugLogger() *slogLogger {
+	defaultLogger := slog.Default()
+	if logger := c.debugSlog.Load(); logger != nil && logger.logger == defaultLogger {
+		return logger
+	}
+
+	logger := newSlogLogger(defaultLogger, SlogConfig{LogBodies: true})
+	logger.level = slog.LevelInfo
+	c.debugSlog.Store(logger)
+	return logger
+}
+
+// logAttempt logs the sent attempt of the request.
+func (l *slogLogger) logAttempt(
+	ctx context.Context, token string, req *APIRequest, resp *APIResponse, err error, duration time.Duration) {
+	level := l.level
+	if err != nil || (resp != nil && resp.StatusCode >= http.StatusBadRequest) {
+		level = slog.LevelWarn
+	}
+
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := append(l.requestAttrs(token, req), slog.Duration("duration", duration))
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	if l.logBodies {
+		attrs = append(attrs, slog.String("request_body", l.requestBody(req)))
+		if resp != nil {
+			attrs = append(attrs, slog.String("response_body", l.body(resp.Body)))
+		}
+	}
+
+	l.logger.LogAttrs(ctx, level, "MG TRANSPORT API request", attrs...)
+}
+
+// logRetry logs the retry of the failed attempt.
+func (l *slogLogger) logRetry(ctx context.Context, token string, req *APIRequest, delay time.Duration) {
+	attrs := append(l.requestAttrs(token, req), slog.Duration("delay", delay))
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "MG TRANSPORT API request retry", attrs...)
+}
+
+func (l *slogLogger) requestAttrs(token string, req *APIRequest) []slog.Attr {
+	return []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("route", req.Route),
+		slog.Int("attempt", req.Attempt),
+		slog.String("token", maskToken(token)),
+	}
+}
+
+func (l *slogLogger) requestBody(req *APIRequest) string {
+	if req.body.seeker != nil || strings.Contains(req.Path, "/files/upload") {
+		return "[file data]"
+	}
+
+	return l.body(req.body.data)
+}
+
+// body returns the redacted body cut to the size limit.
+func (l *slogLogger) body(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ""
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "[non-JSON body, " + strconv.Itoa(len(data)) + " bytes]"
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(l.redactValue(value, false)); err != nil {
+		return "[non-JSON body, " + strconv.Itoa(len(data)) + " bytes]"
+	}
+
+	return truncateLogBody(strings.TrimSuffix(buf.String(), "\n"), l.maxBodySize)
+}
+
+// redactValue replaces the values of the redacted fields. Customer names are redacted only inside
+// the "customer" objects because other entities use the same field names for non-personal data.
+func (l *slogLogger) redactValue(value any, customer bool) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			_, redacted := l.redact[key]
+			if _, name := customerRedactFields[key]; customer && name {
+				redacted = true
+			}
+
+			if redacted {
+				if item != nil && item != "" {
+					typed[key] = redactedValue
+				}
+				continue
+			}
+
+			typed[key] = l.redactValue(item, key == "customer")
+		}
+	case []any:
+		for i, item := range typed {
+			typed[i] = l.redactValue(item, false)
+		}
+	}
+
+	return value
+}
+
+// truncateLogBody cuts the body to the size limit without breaking UTF-8 sequences.
+func truncateLogBody(body string, limit int) string {
+	if len(body) <= limit {
+		return body
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+
+	return body[:cut] + "...[truncated, " + strconv.Itoa(len(body)) + " bytes]"
+}
+
+// maskToken hides the token leaving only its beginning, so the tokens can be told apart in the logs.
+func maskToken(token string) string {
+	if len(token) <= maskedTokenSize*2 {
+		return "***"
+	}
+
+	return token[:maskedTokenSize] + "***"
+}
@@ -0,0 +1,253 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSlog() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), &buf
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestSlog_Attempts(t *testing.T) {
+	srv := newRecordingServer(1, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	logger, buf := newTestSlog()
+	c := retryingClient(srv.URL).WithSlog(logger, SlogConfig{})
+	c.Token = "0123456789abcdef"
+	c.Debug = true
+
+	_, _, err := c.Messages(SendData{Channel: 1, ExternalChatID: "chat"})
+	require.NoError(t, err)
+
+	lines := decodeLogLines(t, buf)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "WARN", lines[0]["level"])
+	assert.Equal(t, "MG TRANSPORT API request", lines[0]["msg"])
+	assert.Equal(t, "POST", lines[0]["method"])
+	assert.Equal(t, "/messages", lines[0]["route"])
+	assert.Equal(t, float64(1), lines[0]["attempt"])
+	assert.Equal(t, float64(http.StatusTooManyRequests), lines[0]["status"])
+	assert.Equal(t, "0123***", lines[0]["token"])
+	assert.Contains(t, lines[0], "duration")
+	assert.NotContains(t, lines[0], "request_body")
+
+	assert.Equal(t, "INFO", lines[1]["level"])
+	assert.Equal(t, "MG TRANSPORT API request retry", lines[1]["msg"])
+	assert.Contains(t, lines[1], "delay")
+
+	assert.Equal(t, "DEBUG", lines[2]["level"])
+	assert.Equal(t, float64(2), lines[2]["attempt"])
+	assert.Equal(t, float64(http.StatusOK), lines[2]["status"])
+
+	assert.NotContains(t, buf.String(), c.Token)
+}
+
+func TestSlog_NetworkError(t *testing.T) {
+	logger, buf := newTestSlog()
+	c := New("http://127.0.0.1:1", "token").WithSlog(logger, SlogConfig{})
+
+	_, _, err := c.TransportTemplates()
+	require.Error(t, err)
+
+	lines := decodeLogLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "WARN", lines[0]["level"])
+	assert.NotContains(t, lines[0], "status")
+	assert.NotEmpty(t, lines[0]["error"])
+	assert.Equal(t, "***", lines[0]["token"])
+}
+
+func TestSlog_Bodies(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	logger, buf := newTestSlog()
+	c := New(srv.URL, "token").WithSlog(logger, SlogConfig{LogBodies: true})
+
+	_, _, err := c.Messages(SendData{
+		Channel:        1,
+		ExternalChatID: "chat",
+		Customer: Customer{
+			ExternalID: "customer",
+			Nickname:   "johndoe",
+			Firstname:  "John",
+			Lastname:   "Doe",
+			Phone:      "+79990000000",
+			Email:      "john@example.com",
+		},
+		Message: Message{ExternalID: "message", Type: MsgTypeText, Text: "Hello <b>John</b>"},
+	})
+	require.NoError(t, err)
+
+	lines := decodeLogLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, `{"id":"file_id","message_id":1}`, lines[0]["response_body"], "keys are sorted by the redaction")
+
+	var body struct {
+		Customer       map[string]any `json:"customer"`
+		Message        map[string]any `json:"message"`
+		ExternalChatID string         `json:"external_chat_id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]["request_body"].(string)), &body))
+	assert.Equal(t, "chat", body.ExternalChatID)
+	assert.Equal(t, "Hello <b>John</b>", body.Message["text"])
+	assert.Equal(t, "customer", body.Customer["external_id"])
+	for _, field := range []string{"nickname", "first_name", "last_name", "phone", "email"} {
+		assert.Equal(t, redactedValue, body.Customer[field], field)
+	}
+
+	for _, secret := range []string{"johndoe", "John\"", "Doe", "+79990000000", "john@example.com"} {
+		assert.NotContains(t, buf.String(), secret)
+	}
+}
+
+func TestSlog_BodyRedaction(t *testing.T) {
+	l := &slogLogger{maxBodySize: DefaultMaxLogBodySize, redact: map[string]struct{}{"phone": {}, "token": {}}}
+
+	assert.Equal(t,
+		`{"items":[{"phone":"[REDACTED]"}],"name":"template","token":"[REDACTED]","user":{"first_name":"Bot"}}`,
+		l.body([]byte(`{"user":{"first_name":"Bot"},"name":"template","token":"x","items":[{"phone":"1"}]}`)))
+	assert.Equal(t, `{"customer":{"email":"a@b.c","first_name":"[REDACTED]","nickname":""}}`,
+		l.body([]byte(`{"customer":{"first_name":"John","nickname":"","email":"a@b.c"}}`)))
+	assert.Equal(t, `{"id":12345678901234567890}`, l.body([]byte(`{"id":12345678901234567890}`)))
+	assert.Equal(t, "[non-JSON body, 5 bytes]", l.body([]byte("hello")))
+	assert.Equal(t, "", l.body(nil))
+}
+
+func TestSlog_BodyLimit(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	logger, buf := newTestSlog()
+	c := New(srv.URL, "token").WithSlog(logger, SlogConfig{LogBodies: true, MaxBodySize: 20})
+
+	_, _, err := c.Messages(SendData{Channel: 1, ExternalChatID: "чат"})
+	require.NoError(t, err)
+
+	lines := decodeLogLines(t, buf)
+	require.Len(t, lines, 1)
+	body := lines[0]["request_body"].(string)
+	assert.True(t, strings.HasPrefix(body, `{"channel":1,`), body)
+	assert.Contains(t, body, "...[truncated, ")
+	assert.Equal(t, `{"id":"file_id","mes...[truncated, 31 bytes]`, lines[0]["response_body"])
+
+	assert.Equal(t, "ab...[truncated, 4 bytes]", truncateLogBody("abcd", 2))
+	assert.Equal(t, "a...[truncated, 3 bytes]", truncateLogBody("aд", 2), "multibyte runes are not split")
+}
+
+func TestSlog_DisabledLevel(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	c := New(srv.URL, "token").WithSlog(logger, SlogConfig{LogBodies: true})
+
+	_, _, err := c.Messages(SendData{Channel: 1})
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "successful attempts are logged at the debug level")
+}
+
+func TestMaskToken(t *testing.T) {
+	assert.Equal(t, "***", maskToken(""))
+	assert.Equal(t, "***", maskToken("12345678"))
+	assert.Equal(t, "1234***", maskToken("123456789"))
+}
+
+func debugSendData() SendData {
+	return SendData{
+		Channel:        1,
+		ExternalChatID: "chat",
+		Customer:       Customer{ExternalID: "customer", Firstname: "John", Phone: "+79990000000"},
+		Message:        Message{ExternalID: "message", Type: MsgTypeText, Text: strings.Repeat("a", DefaultMaxLogBodySize)},
+	}
+}
+
+func TestSlog_DebugBasicLogger(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := New(srv.URL, "0123456789abcdef").WithLogger(log.New(&buf, "", 0))
+	c.Debug = true
+
+	_, _, err := c.Messages(debugSendData())
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "MG TRANSPORT API Request: POST "), lines[0])
+	assert.Contains(t, lines[0], "0123***")
+	assert.Contains(t, lines[0], "...[truncated, ")
+	assert.Equal(t, `MG TRANSPORT API Response: {"id":"file_id","message_id":1}`, lines[1])
+	for _, secret := range []string{"0123456789abcdef", "John", "+79990000000"} {
+		assert.NotContains(t, buf.String(), secret)
+	}
+}
+
+func TestSlog_DebugDefaultLogger(t *testing.T) {
+	srv := newRecordingServer(0, http.StatusOK)
+	defer srv.Close()
+
+	logger, buf := newTestSlog()
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	c := New(srv.URL, "0123456789abcdef")
+	c.Debug = true
+
+	_, _, err := c.Messages(debugSendData())
+	require.NoError(t, err)
+
+	lines := decodeLogLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "0123***", lines[0]["token"])
+	assert.Contains(t, lines[0]["request_body"], "...[truncated, ")
+	assert.Equal(t, `{"id":"file_id","message_id":1}`, lines[0]["response_body"])
+	for _, secret := range []string{"0123456789abcdef", "John", "+79990000000"} {
+		assert.NotContains(t, buf.String(), secret)
+	}
+}
+
+func TestSlog_DebugLoggerReused(t *testing.T) {
+	c := New("https://mg.example.com", "token")
+	c.Debug = true
+
+	logger := c.structuredLogger()
+	require.NotNil(t, logger)
+	assert.Same(t, logger, c.structuredLogger())
+	assert.Same(t, logger, c.debugLogger())
+
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	updated := c.structuredLogger()
+	assert.NotSame(t, logger, updated, "the logger should follow slog.Default()")
+	assert.Same(t, slog.Default(), updated.logger)
+	assert.Same(t, updated, c.structuredLogger())
+}
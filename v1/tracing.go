@@ -0,0 +1,321 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader is the W3C Trace Context header which carries the trace and the parent span IDs.
+const TraceParentHeader = "traceparent"
+
+// Span attribute keys set by the MgClient and the WebhookHandler.
+const (
+	AttrMethod         = "http.request.method"
+	AttrStatusCode     = "http.response.status_code"
+	AttrRoute          = "mg.route"
+	AttrChannelID      = "mg.channel_id"
+	AttrExternalChatID = "mg.external_chat_id"
+	AttrRetries        = "mg.retries"
+	AttrWebhookType    = "mg.webhook.type"
+	AttrWebhookID      = "mg.webhook.meta_id"
+)
+
+const (
+	traceParentVersion = "00"
+	traceFlagSampled   = 0x01
+)
+
+// NoopTracer doesn't record spans. It is used by the client by default. The span context found in the context
+// is still propagated, so the traces started by the caller are continued by the MG requests.
+var NoopTracer Tracer = &noopTracer{}
+
+// Tracer creates the spans for the API calls and the webhooks. It can be implemented on top of OpenTelemetry
+// or any other tracing library. Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start creates the span which is the child of the span found in ctx and returns the context with the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is the single traced operation.
+type Span interface {
+	// SpanContext returns the IDs of the span. They are sent to MG in the traceparent header if the context is valid.
+	SpanContext() SpanContext
+	// SetAttributes adds the attributes to the span.
+	SetAttributes(attrs ...Attribute)
+	// SetError marks the span as failed.
+	SetError(err error)
+	// End finishes the span.
+	End()
+}
+
+// Attribute is the key-value pair attached to the span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanContext contains the W3C Trace Context identifiers of the span.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+type spanContextKey struct{}
+
+// IsValid returns true if both trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent returns the traceparent header value, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return traceParentVersion + "-" + hex.EncodeToString(sc.TraceID[:]) + "-" +
+		hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceParent parses the traceparent header value. It returns false if the value is malformed.
+func ParseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return sc, false
+	}
+
+	if len(parts[1]) != hex.EncodedLen(len(sc.TraceID)) || len(parts[2]) != hex.EncodedLen(len(sc.SpanID)) ||
+		len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags&traceFlagSampled != 0
+
+	return sc, sc.IsValid()
+}
+
+// ContextWithSpanContext returns the context with the provided span context. Tracers use it as the parent
+// of the spans created with this context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored by ContextWithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// SpanData describes the finished span of the SimpleTracer.
+type SpanData struct {
+	Name        string
+	SpanContext SpanContext
+	// Parent is the span context of the parent span. It is invalid for the root spans.
+	Parent     SpanContext
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Err        error
+}
+
+// Attribute returns the value of the attribute with the provided key.
+func (d SpanData) Attribute(key string) (any, bool) {
+	for i := len(d.Attributes) - 1; i >= 0; i-- {
+		if d.Attributes[i].Key == key {
+			return d.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// SimpleTracer is the minimal Tracer which generates W3C Trace Context identifiers and passes every finished span
+// to OnEnd. It can be used to log the spans or to export them to the tracing system without additional dependencies.
+//
+// Example:
+//
+//	tracer := &SimpleTracer{OnEnd: func(span SpanData) {
+//		log.Printf("%s %s took %s", span.SpanContext.TraceParent(), span.Name, span.End.Sub(span.Start))
+//	}}
+//	client := New("https://message-gateway.url", "token").WithTracer(tracer)
+type SimpleTracer struct {
+	OnEnd func(span SpanData)
+}
+
+// Start implements Tracer.
+func (t *SimpleTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, hasParent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{Sampled: true}
+	if hasParent {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		_, _ = rand.Read(sc.TraceID[:])
+	}
+	_, _ = rand.Read(sc.SpanID[:])
+
+	span := &simpleSpan{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			SpanContext: sc,
+			Parent:      parent,
+			Start:       time.Now(),
+			Attributes:  append([]Attribute{}, attrs...),
+		},
+	}
+	return ContextWithSpanContext(ctx, sc), span
+}
+
+type simpleSpan struct {
+	tracer *SimpleTracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+func (s *simpleSpan) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *simpleSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *simpleSpan) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+func (s *simpleSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.tracer.OnEnd != nil {
+		s.tracer.OnEnd(data)
+	}
+}
+
+// WithTracer sets the tracer which creates the span for every API call into the Client.
+func (c *MgClient) WithTracer(tracer Tracer) *MgClient {
+	c.tracer = tracer
+	return c
+}
+
+// startRequestSpan starts the span of the API call and sets the traceparent header of the request.
+func (c *MgClient) startRequestSpan(ctx context.Context, req *APIRequest) (context.Context, Span) {
+	tracer := NoopTracer
+	attrs := []Attribute{{AttrMethod, req.Method}, {AttrRoute, req.Route}}
+	if c.tracer != nil {
+		tracer = c.tracer
+		attrs = append(attrs, requestTraceAttributes(req)...)
+	}
+
+	ctx, span := tracer.Start(ctx, req.Method+" "+req.Route, attrs...)
+	if sc := span.SpanContext(); sc.IsValid() {
+		req.Header.Set(TraceParentHeader, sc.TraceParent())
+	}
+	return ctx, span
+}
+
+// endRequestSpan adds the call results to the span and finishes it.
+func endRequestSpan(span Span, req *APIRequest, resp *APIResponse, err error) {
+	attrs := []Attribute{{AttrRetries, max(req.Attempt-1, 0)}}
+	if resp != nil {
+		attrs = append(attrs, Attribute{AttrStatusCode, resp.StatusCode})
+	}
+
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+// traceTarget contains the request and webhook fields which identify the chat.
+type traceTarget struct {
+	Channel        uint64 `json:"channel"`
+	ChannelID      uint64 `json:"channel_id"`
+	ExternalChatID string `json:"external_chat_id"`
+}
+
+// attributes returns the channel ID and the external chat ID attributes if they are present.
+func (t traceTarget) attributes() []Attribute {
+	var attrs []Attribute
+	if channel := max(t.Channel, t.ChannelID); channel != 0 {
+		attrs = append(attrs, Attribute{AttrChannelID, channel})
+	}
+	if t.ExternalChatID != "" {
+		attrs = append(attrs, Attribute{AttrExternalChatID, t.ExternalChatID})
+	}
+	return attrs
+}
+
+// requestTraceAttributes returns the channel ID and the external chat ID of the request. They are taken
+// from the path of the channel routes and from the top-level fields of the buffered JSON bodies.
+func requestTraceAttributes(req *APIRequest) []Attribute {
+	var target traceTarget
+	if data := req.body.data; req.body.seeker == nil && len(data) > 0 && data[0] == '{' {
+		_ = json.Unmarshal(data, &target)
+	}
+
+	path, _, _ := strings.Cut(req.Path, "?")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "channels" {
+		if id, err := strconv.ParseUint(segments[1], 10, 64); err == nil {
+			target.Channel = id
+		}
+	}
+
+	return target.attributes()
+}
+
+type noopTracer struct{}
+
+func (t *noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	sc, _ := SpanContextFromContext(ctx)
+	return ctx, noopSpan{sc: sc}
+}
+
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (s noopSpan) SpanContext() SpanContext { return s.sc }
+
+func (s noopSpan) SetAttributes(...Attribute) {}
+
+func (s noopSpan) SetError(error) {}
+
+func (s noopSpan) End() {}
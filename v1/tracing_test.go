@@ -0,0 +1,194 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTracer struct {
+	SimpleTracer
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func newRecordingTracer() *recordingTracer {
+	tracer := &recordingTracer{}
+	tracer.OnEnd = func(span SpanData) {
+		tracer.mu.Lock()
+		defer tracer.mu.Unlock()
+		tracer.spans = append(tracer.spans, span)
+	}
+	return tracer
+}
+
+func newTraceParentServer(headers *[]string, status int) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*headers = append(*headers, r.Header.Get(TraceParentHeader))
+		mu.Unlock()
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"message_id":1}`))
+	}))
+}
+
+func TestTracing_ClientSpan(t *testing.T) {
+	var headers []string
+	srv := newTraceParentServer(&headers, http.StatusOK)
+	defer srv.Close()
+
+	tracer := newRecordingTracer()
+	c := New(srv.URL, "token").WithTracer(tracer)
+
+	_, _, err := c.Messages(SendData{Channel: 7, ExternalChatID: "chat"})
+	require.NoError(t, err)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "POST /messages", span.Name)
+	assert.False(t, span.Parent.IsValid())
+	assert.NoError(t, span.Err)
+	assert.Equal(t, []string{span.SpanContext.TraceParent()}, headers)
+
+	for key, expected := range map[string]any{
+		AttrMethod:         http.MethodPost,
+		AttrRoute:          "/messages",
+		AttrChannelID:      uint64(7),
+		AttrExternalChatID: "chat",
+		AttrStatusCode:     http.StatusOK,
+		AttrRetries:        0,
+	} {
+		value, ok := span.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, value, key)
+	}
+}
+
+func TestTracing_ClientSpanError(t *testing.T) {
+	var headers []string
+	srv := newTraceParentServer(&headers, http.StatusBadRequest)
+	defer srv.Close()
+
+	parent, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+
+	tracer := newRecordingTracer()
+	c := New(srv.URL, "token").WithTracer(tracer)
+
+	_, _, err := c.DeactivateTransportChannelContext(ContextWithSpanContext(context.Background(), parent), 3)
+	require.Error(t, err)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, parent, span.Parent)
+	assert.Equal(t, parent.TraceID, span.SpanContext.TraceID)
+	assert.NotEqual(t, parent.SpanID, span.SpanContext.SpanID)
+	assert.Error(t, span.Err)
+
+	channel, _ := span.Attribute(AttrChannelID)
+	assert.Equal(t, uint64(3), channel)
+	status, _ := span.Attribute(AttrStatusCode)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{span.SpanContext.TraceParent()}, headers)
+}
+
+func TestTracing_PropagatesParentWithoutTracer(t *testing.T) {
+	var headers []string
+	srv := newTraceParentServer(&headers, http.StatusOK)
+	defer srv.Close()
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	parent, ok := ParseTraceParent(traceParent)
+	require.True(t, ok)
+
+	c := New(srv.URL, "token")
+	_, _, err := c.Messages(SendData{Channel: 1})
+	require.NoError(t, err)
+	_, _, err = c.MessagesContext(ContextWithSpanContext(context.Background(), parent), SendData{Channel: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", traceParent}, headers)
+}
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.TraceParent())
+
+	sc, ok = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	require.True(t, ok, "future versions can have additional fields")
+	assert.False(t, sc.Sampled)
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-x1",
+	} {
+		_, ok := ParseTraceParent(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestTracing_WebhookSpan(t *testing.T) {
+	tracer := newRecordingTracer()
+	var callbackSpan SpanContext
+	handler := &WebhookHandler{
+		Tracer: tracer,
+		OnMessageSent: func(ctx context.Context, data MessageWebhookData) (TransportResponse, error) {
+			callbackSpan, _ = SpanContextFromContext(ctx)
+			return TransportResponse{}, errors.New("failed")
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(
+		`{"type":"message_sent","meta":{"id":10,"timestamp":1},"data":{"channel_id":5,"external_chat_id":"chat"}}`))
+	req.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "webhook message_sent", span.Name)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", span.Parent.TraceParent())
+	assert.Equal(t, span.SpanContext, callbackSpan)
+	assert.EqualError(t, span.Err, "failed")
+
+	for key, expected := range map[string]any{
+		AttrWebhookType:    "message_sent",
+		AttrWebhookID:      uint64(10),
+		AttrChannelID:      uint64(5),
+		AttrExternalChatID: "chat",
+	} {
+		value, ok := span.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, value, key)
+	}
+}
+
+func TestTracing_WebhookUnknownType(t *testing.T) {
+	tracer := newRecordingTracer()
+	handler := &WebhookHandler{Tracer: tracer}
+
+	rec := serveWebhook(handler, http.MethodPost, `{"type":"unknown","meta":{"id":1},"data":{}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	require.Len(t, tracer.spans, 1)
+	assert.ErrorIs(t, tracer.spans[0].Err, ErrUnknownWebhookType)
+	assert.False(t, tracer.spans[0].Parent.IsValid())
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
@@ -85,12 +86,19 @@ const (
 
 // MgClient type.
 type MgClient struct {
-	URL        string       `json:"url"`
-	Token      string       `json:"token"`
-	Debug      bool         `json:"debug"`
-	httpClient *http.Client `json:"-"`
-	logger     BasicLogger  `json:"-"`
-	limiter    Limiter      `json:"-"`
+	URL         string                     `json:"url"`
+	Token       string                     `json:"token"`
+	Debug       bool                       `json:"debug"`
+	httpClient  *http.Client               `json:"-"`
+	logger      BasicLogger                `json:"-"`
+	slog        *slogLogger                `json:"-"`
+	debugSlog   atomic.Pointer[slogLogger] `json:"-"`
+	limiter     Limiter                    `json:"-"`
+	ownsLimiter bool                       `json:"-"`
+	retryPolicy RetryPolicy                `json:"-"`
+	metrics     Metrics                    `json:"-"`
+	tracer      Tracer                     `json:"-"`
+	middlewares []Middleware               `json:"-"`
 }
 
 // Channel type.
@@ -498,6 +506,11 @@ type MessageSentError struct {
 	ExternalCode string    `json:"external_code,omitempty"`
 }
 
+// Error implements error. It allows returning MessageSentError from the AsyncSender.Send function.
+func (e *MessageSentError) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
 // MessageWebhookData request data.
 type MessageWebhookData struct {
 	ID                int64               `json:"id"`
@@ -1,6 +1,12 @@
 package v1
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
 
 type WebhookType string
 
@@ -44,7 +50,7 @@ func (w WebhookRequest) IsTemplateWebhook() bool {
 // MessageWebhookData returns the message data from webhook contents.
 //
 // Note: this call will not fail even if underlying data is not related to the messages.
-// Use IsMessageWebhook to mitigate this.
+// Use IsMessageWebhook to mitigate this or use DecodeMessageWebhookData which returns an error.
 func (w WebhookRequest) MessageWebhookData() (wd MessageWebhookData) {
 	_ = json.Unmarshal(w.Data, &wd)
 	return
@@ -53,7 +59,7 @@ func (w WebhookRequest) MessageWebhookData() (wd MessageWebhookData) {
 // ReactionWebhookData returns the reaction data from webhook contents.
 //
 // Note: this call will not fail even if underlying data is not related to the reactions.
-// Use IsReactionWebhook to mitigate this.
+// Use IsReactionWebhook to mitigate this or use DecodeReactionWebhookData which returns an error.
 func (w WebhookRequest) ReactionWebhookData() (wd ReactionWebhookData) {
 	_ = json.Unmarshal(w.Data, &wd)
 	return
@@ -88,3 +94,272 @@ func (w WebhookRequest) TemplateDeleteWebhookData() (wd TemplateDeleteWebhookDat
 	_ = json.Unmarshal(w.Data, &wd)
 	return
 }
+
+// ErrWebhookTypeMismatch is returned by the strict decoders if the webhook type doesn't match the requested data.
+var ErrWebhookTypeMismatch = errors.New("webhook type mismatch")
+
+// ErrUnknownWebhookType is returned by DecodeWebhook if the webhook type is not supported.
+var ErrUnknownWebhookType = errors.New("unknown webhook type")
+
+// ErrWebhookDataMissing is returned by the strict decoders if the webhook data is missing or null.
+var ErrWebhookDataMissing = errors.New("webhook data is missing")
+
+// DecodeOption configures the strict webhook decoders.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	disallowUnknownFields bool
+}
+
+// DisallowUnknownFields makes the decoder return an error if the payload contains unknown fields.
+func DisallowUnknownFields() DecodeOption {
+	return func(o *decodeOptions) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// DecodeMessageWebhookData returns the message data from webhook contents.
+// It fails if the webhook is not related to the messages or if the data cannot be decoded.
+func (w WebhookRequest) DecodeMessageWebhookData(opts ...DecodeOption) (MessageWebhookData, error) {
+	return decodeWebhookData[MessageWebhookData](w, opts,
+		MessageSendWebhookType, MessageUpdateWebhookType, MessageDeleteWebhookType, MessageReadWebhookType)
+}
+
+// DecodeReactionWebhookData returns the reaction data from webhook contents.
+// It fails if the webhook is not related to the reactions or if the data cannot be decoded.
+func (w WebhookRequest) DecodeReactionWebhookData(opts ...DecodeOption) (ReactionWebhookData, error) {
+	return decodeWebhookData[ReactionWebhookData](w, opts, ReactionAddWebhookType, ReactionDeleteWebhookType)
+}
+
+// DecodeTemplateCreateWebhookData returns new template data from webhook contents.
+// It fails if the webhook type is not TemplateCreateWebhookType or if the data cannot be decoded.
+func (w WebhookRequest) DecodeTemplateCreateWebhookData(opts ...DecodeOption) (TemplateCreateWebhookData, error) {
+	return decodeWebhookData[TemplateCreateWebhookData](w, opts, TemplateCreateWebhookType)
+}
+
+// DecodeTemplateUpdateWebhookData returns existing template data from webhook contents.
+// It fails if the webhook type is not TemplateUpdateWebhookType or if the data cannot be decoded.
+func (w WebhookRequest) DecodeTemplateUpdateWebhookData(opts ...DecodeOption) (TemplateUpdateWebhookData, error) {
+	return decodeWebhookData[TemplateUpdateWebhookData](w, opts, TemplateUpdateWebhookType)
+}
+
+// DecodeTemplateDeleteWebhookData returns existing template data from webhook contents.
+// It fails if the webhook type is not TemplateDeleteWebhookType or if the data cannot be decoded.
+func (w WebhookRequest) DecodeTemplateDeleteWebhookData(opts ...DecodeOption) (TemplateDeleteWebhookData, error) {
+	return decodeWebhookData[TemplateDeleteWebhookData](w, opts, TemplateDeleteWebhookType)
+}
+
+func decodeWebhookData[T any](w WebhookRequest, opts []DecodeOption, types ...WebhookType) (T, error) {
+	var data T
+	if !slices.Contains(types, w.Type) {
+		return data, fmt.Errorf("%w: %s", ErrWebhookTypeMismatch, w.Type)
+	}
+
+	if trimmed := bytes.TrimSpace(w.Data); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return data, fmt.Errorf("cannot decode %s webhook data: %w", w.Type, ErrWebhookDataMissing)
+	}
+
+	if err := decodeJSON(w.Data, &data, opts); err != nil {
+		return data, fmt.Errorf("cannot decode %s webhook data: %w", w.Type, err)
+	}
+
+	return data, nil
+}
+
+func decodeJSON(data []byte, out interface{}, opts []DecodeOption) error {
+	var o decodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if o.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(out); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+
+	return nil
+}
+
+// WebhookEvent is a decoded webhook returned by DecodeWebhook. Use a type switch to get the event data:
+//
+//	switch e := event.(type) {
+//	case MessageSentEvent:
+//		log.Printf("message %d: %s", e.Data.ID, e.Data.Content)
+//	case TemplateDeleteEvent:
+//		log.Printf("template %s was deleted", e.Data.Code)
+//	}
+type WebhookEvent interface {
+	// WebhookType returns the type of the webhook.
+	WebhookType() WebhookType
+	// WebhookMeta returns the webhook metadata.
+	WebhookMeta() TransportRequestMeta
+}
+
+// EventMeta contains the webhook metadata and is embedded into every WebhookEvent.
+type EventMeta struct {
+	Meta TransportRequestMeta
+}
+
+// WebhookMeta returns the webhook metadata.
+func (e EventMeta) WebhookMeta() TransportRequestMeta {
+	return e.Meta
+}
+
+// MessageSentEvent is the message_sent webhook.
+type MessageSentEvent struct {
+	EventMeta
+	Data MessageWebhookData
+}
+
+// MessageUpdatedEvent is the message_updated webhook.
+type MessageUpdatedEvent struct {
+	EventMeta
+	Data MessageWebhookData
+}
+
+// MessageDeletedEvent is the message_deleted webhook.
+type MessageDeletedEvent struct {
+	EventMeta
+	Data MessageWebhookData
+}
+
+// MessageReadEvent is the message_read webhook.
+type MessageReadEvent struct {
+	EventMeta
+	Data MessageWebhookData
+}
+
+// ReactionAddEvent is the reaction_add webhook.
+type ReactionAddEvent struct {
+	EventMeta
+	Data ReactionWebhookData
+}
+
+// ReactionDeleteEvent is the reaction_delete webhook.
+type ReactionDeleteEvent struct {
+	EventMeta
+	Data ReactionWebhookData
+}
+
+// TemplateCreateEvent is the template_create webhook.
+type TemplateCreateEvent struct {
+	EventMeta
+	Data TemplateCreateWebhookData
+}
+
+// TemplateUpdateEvent is the template_update webhook.
+type TemplateUpdateEvent struct {
+	EventMeta
+	Data TemplateUpdateWebhookData
+}
+
+// TemplateDeleteEvent is the template_delete webhook.
+type TemplateDeleteEvent struct {
+	EventMeta
+	Data TemplateDeleteWebhookData
+}
+
+// WebhookType returns MessageSendWebhookType.
+func (MessageSentEvent) WebhookType() WebhookType {
+	return MessageSendWebhookType
+}
+
+// WebhookType returns MessageUpdateWebhookType.
+func (MessageUpdatedEvent) WebhookType() WebhookType {
+	return MessageUpdateWebhookType
+}
+
+// WebhookType returns MessageDeleteWebhookType.
+func (MessageDeletedEvent) WebhookType() WebhookType {
+	return MessageDeleteWebhookType
+}
+
+// WebhookType returns MessageReadWebhookType.
+func (MessageReadEvent) WebhookType() WebhookType {
+	return MessageReadWebhookType
+}
+
+// WebhookType returns ReactionAddWebhookType.
+func (ReactionAddEvent) WebhookType() WebhookType {
+	return ReactionAddWebhookType
+}
+
+// WebhookType returns ReactionDeleteWebhookType.
+func (ReactionDeleteEvent) WebhookType() WebhookType {
+	return ReactionDeleteWebhookType
+}
+
+// WebhookType returns TemplateCreateWebhookType.
+func (TemplateCreateEvent) WebhookType() WebhookType {
+	return TemplateCreateWebhookType
+}
+
+// WebhookType returns TemplateUpdateWebhookType.
+func (TemplateUpdateEvent) WebhookType() WebhookType {
+	return TemplateUpdateWebhookType
+}
+
+// WebhookType returns TemplateDeleteWebhookType.
+func (TemplateDeleteEvent) WebhookType() WebhookType {
+	return TemplateDeleteWebhookType
+}
+
+// DecodeWebhook decodes the webhook body into the WebhookEvent.
+// It fails if the body is malformed, the webhook type is unknown or the data doesn't match the type.
+func DecodeWebhook(body []byte, opts ...DecodeOption) (WebhookEvent, error) {
+	var wh WebhookRequest
+	if err := decodeJSON(body, &wh, opts); err != nil {
+		return nil, fmt.Errorf("cannot decode webhook: %w", err)
+	}
+
+	return wh.Event(opts...)
+}
+
+// Event returns the WebhookEvent for the webhook. It fails if the webhook type is unknown or the data
+// cannot be decoded.
+func (w WebhookRequest) Event(opts ...DecodeOption) (WebhookEvent, error) {
+	meta := EventMeta{Meta: w.Meta}
+	switch w.Type {
+	case MessageSendWebhookType:
+		return newEvent(w, opts, func(data MessageWebhookData) WebhookEvent { return MessageSentEvent{meta, data} })
+	case MessageUpdateWebhookType:
+		return newEvent(w, opts, func(data MessageWebhookData) WebhookEvent { return MessageUpdatedEvent{meta, data} })
+	case MessageDeleteWebhookType:
+		return newEvent(w, opts, func(data MessageWebhookData) WebhookEvent { return MessageDeletedEvent{meta, data} })
+	case MessageReadWebhookType:
+		return newEvent(w, opts, func(data MessageWebhookData) WebhookEvent { return MessageReadEvent{meta, data} })
+	case ReactionAddWebhookType:
+		return newEvent(w, opts, func(data ReactionWebhookData) WebhookEvent { return ReactionAddEvent{meta, data} })
+	case ReactionDeleteWebhookType:
+		return newEvent(w, opts, func(data ReactionWebhookData) WebhookEvent { return ReactionDeleteEvent{meta, data} })
+	case TemplateCreateWebhookType:
+		return newEvent(w, opts, func(data TemplateCreateWebhookData) WebhookEvent {
+			return TemplateCreateEvent{meta, data}
+		})
+	case TemplateUpdateWebhookType:
+		return newEvent(w, opts, func(data TemplateUpdateWebhookData) WebhookEvent {
+			return TemplateUpdateEvent{meta, data}
+		})
+	case TemplateDeleteWebhookType:
+		return newEvent(w, opts, func(data TemplateDeleteWebhookData) WebhookEvent {
+			return TemplateDeleteEvent{meta, data}
+		})
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownWebhookType, w.Type)
+}
+
+func newEvent[T any](w WebhookRequest, opts []DecodeOption, build func(T) WebhookEvent) (WebhookEvent, error) {
+	data, err := decodeWebhookData[T](w, opts, w.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return build(data), nil
+}
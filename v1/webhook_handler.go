@@ -0,0 +1,253 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type webhookContextKey struct{}
+
+// WebhookHandler is an http.Handler which decodes MessageGateway webhooks and dispatches them to the typed callbacks.
+// Webhooks without a registered callback are answered with 422 Unprocessable Entity, malformed webhooks
+// are answered with 400 Bad Request.
+//
+// Callbacks receive the request context which also contains the decoded webhook (see WebhookFromContext).
+// If a callback returns an error the handler responds with the status from WebhookError or with
+// 500 Internal Server Error and the TransportResponse which contains the error message.
+//
+// Example:
+//
+//	handler := &WebhookHandler{
+//		OnMessageSent: func(ctx context.Context, data MessageWebhookData) (TransportResponse, error) {
+//			id, err := messenger.Send(ctx, data.ExternalChatID, data.Content)
+//			if err != nil {
+//				return NewTransportErrorResponse(MessageErrorGeneral, err.Error()), nil
+//			}
+//			return NewSentMessageResponse(id), nil
+//		},
+//	}
+//	http.Handle("/webhook", handler)
+type WebhookHandler struct {
+	OnMessageSent    func(ctx context.Context, data MessageWebhookData) (TransportResponse, error)
+	OnMessageUpdated func(ctx context.Context, data MessageWebhookData) (TransportResponse, error)
+	OnMessageDeleted func(ctx context.Context, data MessageWebhookData) error
+	OnMessageRead    func(ctx context.Context, data MessageWebhookData) error
+	OnReactionAdd    func(ctx context.Context, data ReactionWebhookData) error
+	OnReactionDelete func(ctx context.Context, data ReactionWebhookData) error
+	OnTemplateCreate func(ctx context.Context, data TemplateCreateWebhookData) (TemplateCreateWebhookResponse, error)
+	OnTemplateUpdate func(ctx context.Context, data TemplateUpdateWebhookData) error
+	OnTemplateDelete func(ctx context.Context, data TemplateDeleteWebhookData) error
+	// OnMessageSentAsync is used instead of OnMessageSent if it's set. It can answer with the Async flag
+	// and confirm the delivery later with AckMessage (see AsyncSender).
+	OnMessageSentAsync func(ctx context.Context, data MessageWebhookData) (WebhookMessageSentResponse, error)
+	// DisallowUnknownFields makes the handler reject webhooks with unknown fields with 400 Bad Request.
+	DisallowUnknownFields bool
+	// Tracer creates the span for every decoded webhook. The traceparent header of the webhook request is used
+	// as the parent if the request context doesn't contain the span context already.
+	Tracer Tracer
+}
+
+// WebhookError can be returned from the WebhookHandler callbacks to respond with the specific status and error code.
+type WebhookError struct {
+	StatusCode int
+	Code       TransportErrorCode
+	Err        error
+}
+
+// Error returns the underlying error message.
+func (e *WebhookError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.StatusCode)
+	}
+
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookFromContext returns the webhook which is being handled by the WebhookHandler.
+func WebhookFromContext(ctx context.Context) (WebhookRequest, bool) {
+	wh, ok := ctx.Value(webhookContextKey{}).(WebhookRequest)
+	return wh, ok
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeWebhookError(rw, &WebhookError{StatusCode: http.StatusMethodNotAllowed})
+		return
+	}
+
+	var opts []DecodeOption
+	if h.DisallowUnknownFields {
+		opts = append(opts, DisallowUnknownFields())
+	}
+
+	body, err := readWebhookBody(req)
+	if err != nil {
+		writeWebhookError(rw, webhookBodyError(err))
+		return
+	}
+
+	var wh WebhookRequest
+	if err := decodeJSON(body, &wh, opts); err != nil {
+		writeWebhookError(rw, &WebhookError{StatusCode: http.StatusBadRequest, Err: err})
+		return
+	}
+
+	ctx, span := h.startSpan(req, wh)
+	defer span.End()
+
+	event, err := wh.Event(opts...)
+	switch {
+	case errors.Is(err, ErrUnknownWebhookType):
+		span.SetError(err)
+		writeWebhookError(rw, &WebhookError{StatusCode: http.StatusUnprocessableEntity, Err: err})
+		return
+	case err != nil:
+		span.SetError(err)
+		writeWebhookError(rw, &WebhookError{StatusCode: http.StatusBadRequest, Err: err})
+		return
+	}
+
+	resp, err := h.dispatch(context.WithValue(ctx, webhookContextKey{}, wh), event)
+	if err != nil {
+		span.SetError(err)
+		writeWebhookError(rw, err)
+		return
+	}
+
+	writeWebhookJSON(rw, http.StatusOK, resp)
+}
+
+// errWebhookNotRegistered is returned by dispatch if there is no callback for the webhook type.
+var errWebhookNotRegistered = errors.New("webhook callback is not registered")
+
+// startSpan starts the span of the webhook dispatch. The span has the webhook type, the meta ID and the chat
+// attributes found in the webhook data.
+func (h *WebhookHandler) startSpan(req *http.Request, wh WebhookRequest) (context.Context, Span) {
+	ctx := req.Context()
+	if h.Tracer == nil {
+		return NoopTracer.Start(ctx, "")
+	}
+
+	if _, ok := SpanContextFromContext(ctx); !ok {
+		if sc, ok := ParseTraceParent(req.Header.Get(TraceParentHeader)); ok {
+			ctx = ContextWithSpanContext(ctx, sc)
+		}
+	}
+
+	var target traceTarget
+	_ = json.Unmarshal(wh.Data, &target)
+
+	attrs := append([]Attribute{{AttrWebhookType, string(wh.Type)}, {AttrWebhookID, wh.Meta.ID}}, target.attributes()...)
+	return h.Tracer.Start(ctx, "webhook "+string(wh.Type), attrs...)
+}
+
+func (h *WebhookHandler) dispatch(ctx context.Context, event WebhookEvent) (interface{}, error) {
+	switch e := event.(type) {
+	case MessageSentEvent:
+		if h.OnMessageSentAsync != nil {
+			return h.OnMessageSentAsync(ctx, e.Data)
+		}
+		if h.OnMessageSent != nil {
+			return h.OnMessageSent(ctx, e.Data)
+		}
+	case MessageUpdatedEvent:
+		if h.OnMessageUpdated != nil {
+			return h.OnMessageUpdated(ctx, e.Data)
+		}
+	case MessageDeletedEvent:
+		if h.OnMessageDeleted != nil {
+			return struct{}{}, h.OnMessageDeleted(ctx, e.Data)
+		}
+	case MessageReadEvent:
+		if h.OnMessageRead != nil {
+			return struct{}{}, h.OnMessageRead(ctx, e.Data)
+		}
+	case ReactionAddEvent:
+		if h.OnReactionAdd != nil {
+			return struct{}{}, h.OnReactionAdd(ctx, e.Data)
+		}
+	case ReactionDeleteEvent:
+		if h.OnReactionDelete != nil {
+			return struct{}{}, h.OnReactionDelete(ctx, e.Data)
+		}
+	case TemplateCreateEvent:
+		if h.OnTemplateCreate != nil {
+			return h.OnTemplateCreate(ctx, e.Data)
+		}
+	case TemplateUpdateEvent:
+		if h.OnTemplateUpdate != nil {
+			return struct{}{}, h.OnTemplateUpdate(ctx, e.Data)
+		}
+	case TemplateDeleteEvent:
+		if h.OnTemplateDelete != nil {
+			return struct{}{}, h.OnTemplateDelete(ctx, e.Data)
+		}
+	}
+
+	return nil, &WebhookError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Err:        fmt.Errorf("%w: %s", errWebhookNotRegistered, event.WebhookType()),
+	}
+}
+
+// readWebhookBody reads the webhook body. ErrWebhookTooLarge is returned if it exceeds LimitResponse.
+func readWebhookBody(req *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, LimitResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > LimitResponse {
+		return nil, ErrWebhookTooLarge
+	}
+
+	return body, nil
+}
+
+// webhookBodyError returns the error response for the readWebhookBody error.
+func webhookBodyError(err error) *WebhookError {
+	if errors.Is(err, ErrWebhookTooLarge) {
+		return &WebhookError{StatusCode: http.StatusRequestEntityTooLarge, Err: err}
+	}
+
+	return &WebhookError{StatusCode: http.StatusBadRequest, Err: err}
+}
+
+func writeWebhookError(rw http.ResponseWriter, err error) {
+	whErr := &WebhookError{StatusCode: http.StatusInternalServerError, Err: err}
+	errors.As(err, &whErr)
+
+	status := whErr.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	code := whErr.Code
+	if code == "" {
+		code = MessageErrorGeneral
+	}
+
+	writeWebhookJSON(rw, status, NewTransportErrorResponse(code, whErr.Error()))
+}
+
+func writeWebhookJSON(rw http.ResponseWriter, status int, data interface{}) {
+	resp, err := json.Marshal(data)
+	if err != nil {
+		status = http.StatusInternalServerError
+		resp, _ = json.Marshal(NewTransportErrorResponse(MessageErrorGeneral, err.Error()))
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(status)
+	_, _ = rw.Write(resp)
+}
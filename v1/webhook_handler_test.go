@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveWebhook(handler http.Handler, method, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, "/webhook", strings.NewReader(body)))
+	return rec
+}
+
+func TestWebhookHandler_MessageSent(t *testing.T) {
+	handler := &WebhookHandler{
+		OnMessageSent: func(ctx context.Context, data MessageWebhookData) (TransportResponse, error) {
+			wh, ok := WebhookFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, uint64(10), wh.Meta.ID)
+			assert.Equal(t, "Hello!", data.Content)
+			return NewSentMessageResponse("external"), nil
+		},
+	}
+
+	rec := serveWebhook(handler, http.MethodPost,
+		`{"type":"message_sent","meta":{"id":10,"timestamp":1},"data":{"id":1,"content":"Hello!"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"external_message_id":"external"}`, rec.Body.String())
+}
+
+func TestWebhookHandler_TemplateCreate(t *testing.T) {
+	handler := &WebhookHandler{
+		OnTemplateCreate: func(_ context.Context, data TemplateCreateWebhookData) (TemplateCreateWebhookResponse, error) {
+			assert.Equal(t, int64(1), data.ChannelID)
+			return TemplateCreateWebhookResponse{Code: "code", VerificationStatus: TemplateStatusApproved}, nil
+		},
+	}
+
+	rec := serveWebhook(handler, http.MethodPost, `{"type":"template_create","data":{"channel_id":1}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"code":"code","verification_status":"approved"}`, rec.Body.String())
+}
+
+func TestWebhookHandler_EmptyResponse(t *testing.T) {
+	var reaction string
+	handler := &WebhookHandler{
+		OnReactionAdd: func(_ context.Context, data ReactionWebhookData) error {
+			reaction = data.NewReaction
+			return nil
+		},
+	}
+
+	rec := serveWebhook(handler, http.MethodPost, `{"type":"reaction_add","data":{"new_reaction":"👍"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{}`, rec.Body.String())
+	assert.Equal(t, "👍", reaction)
+}
+
+func TestWebhookHandler_Errors(t *testing.T) {
+	handler := &WebhookHandler{
+		OnMessageRead: func(context.Context, MessageWebhookData) error {
+			return errors.New("storage is unavailable")
+		},
+		OnMessageDeleted: func(context.Context, MessageWebhookData) error {
+			return &WebhookError{
+				StatusCode: http.StatusBadRequest,
+				Code:       MessageErrorAccessRestricted,
+				Err:        errors.New("chat is blocked"),
+			}
+		},
+		OnReactionAdd: func(context.Context, ReactionWebhookData) error {
+			return &WebhookError{Code: MessageErrorAccessRestricted, Err: errors.New("reactions are disabled")}
+		},
+	}
+
+	cases := []struct {
+		method string
+		body   string
+		status int
+		resp   TransportResponse
+	}{
+		{http.MethodGet, ``, http.StatusMethodNotAllowed,
+			NewTransportErrorResponse(MessageErrorGeneral, "Method Not Allowed")},
+		{http.MethodPost, `{`, http.StatusBadRequest,
+			NewTransportErrorResponse(MessageErrorGeneral, "unexpected EOF")},
+		{http.MethodPost, `{"type":"message_sent","data":{}}`, http.StatusUnprocessableEntity,
+			NewTransportErrorResponse(MessageErrorGeneral, "webhook callback is not registered: message_sent")},
+		{http.MethodPost, `{"type":"unknown","data":{}}`, http.StatusUnprocessableEntity,
+			NewTransportErrorResponse(MessageErrorGeneral, "unknown webhook type: unknown")},
+		{http.MethodPost, `{"type":"message_read","data":{}}`, http.StatusInternalServerError,
+			NewTransportErrorResponse(MessageErrorGeneral, "storage is unavailable")},
+		{http.MethodPost, `{"type":"message_deleted","data":{}}`, http.StatusBadRequest,
+			NewTransportErrorResponse(MessageErrorAccessRestricted, "chat is blocked")},
+		{http.MethodPost, `{"type":"reaction_add","data":{}}`, http.StatusInternalServerError,
+			NewTransportErrorResponse(MessageErrorAccessRestricted, "reactions are disabled")},
+		{http.MethodPost, strings.Repeat(" ", LimitResponse+1), http.StatusRequestEntityTooLarge,
+			NewTransportErrorResponse(MessageErrorGeneral, "webhook body is too large")},
+	}
+
+	for i, c := range cases {
+		rec := serveWebhook(handler, c.method, c.body)
+		assert.Equal(t, c.status, rec.Code, "case %d", i)
+
+		var resp TransportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, c.resp, resp, "case %d", i)
+	}
+}
+
+func TestWebhookHandler_DisallowUnknownFields(t *testing.T) {
+	handler := &WebhookHandler{
+		OnMessageRead:         func(context.Context, MessageWebhookData) error { return nil },
+		DisallowUnknownFields: true,
+	}
+
+	rec := serveWebhook(handler, http.MethodPost, `{"type":"message_read","data":{"id":1}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serveWebhook(handler, http.MethodPost, `{"type":"message_read","data":{"id":1,"unknown":true}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serveWebhook(handler, http.MethodPost, `{"type":"message_read","data":{"id":"1"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
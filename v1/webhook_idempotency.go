@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyReplayedHeader is set to "true" in the responses which were replayed from the IdempotencyStore.
+const IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+// StoredResponse is the webhook response saved in the IdempotencyStore.
+type StoredResponse struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// IdempotencyStore stores the webhook responses by the webhook key.
+type IdempotencyStore interface {
+	// Get returns the stored response. The second value is false if there is no response for the key.
+	Get(ctx context.Context, key string) (StoredResponse, bool, error)
+	// Set stores the response.
+	Set(ctx context.Context, key string, resp StoredResponse) error
+}
+
+// WebhookIdempotencyKey returns the key of the webhook which is used in the IdempotencyStore.
+func WebhookIdempotencyKey(webhookType WebhookType, meta TransportRequestMeta) string {
+	return string(webhookType) + ":" + strconv.FormatUint(meta.ID, 10)
+}
+
+// WebhookDeduplicator replays the saved responses for the webhooks which were already handled.
+// Webhooks are identified by their type and TransportRequestMeta.ID. Only successful (2xx) responses are saved,
+// so the failed webhooks are handled again on redelivery. Concurrent deliveries of the same webhook are
+// handled one at a time.
+//
+// Example:
+//
+//	dedup := &WebhookDeduplicator{Store: NewMemoryIdempotencyStore(10000)}
+//	http.Handle("/webhook", dedup.Handler(&WebhookHandler{...}))
+type WebhookDeduplicator struct {
+	Store IdempotencyStore
+
+	mu       sync.Mutex
+	inflight map[string]*inflightWebhook
+}
+
+type inflightWebhook struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Handler wraps the handler with the webhook deduplication. Webhooks larger than LimitResponse are answered
+// with 413 Request Entity Too Large.
+func (d *WebhookDeduplicator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := readWebhookBody(req)
+		if err != nil {
+			writeWebhookError(rw, webhookBodyError(err))
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var wh struct {
+			Type WebhookType          `json:"type"`
+			Meta TransportRequestMeta `json:"meta"`
+		}
+		if err := json.Unmarshal(body, &wh); err != nil || wh.Meta.ID == 0 {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		key := WebhookIdempotencyKey(wh.Type, wh.Meta)
+		unlock := d.lock(key)
+		defer unlock()
+
+		stored, ok, err := d.Store.Get(req.Context(), key)
+		if err != nil {
+			writeWebhookError(rw, err)
+			return
+		}
+		if ok {
+			writeStoredResponse(rw, stored)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+		if rec.status < http.StatusOK || rec.status >= http.StatusMultipleChoices {
+			return
+		}
+
+		_ = d.Store.Set(req.Context(), key, StoredResponse{
+			StatusCode:  rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+	})
+}
+
+func (d *WebhookDeduplicator) lock(key string) func() {
+	d.mu.Lock()
+	if d.inflight == nil {
+		d.inflight = map[string]*inflightWebhook{}
+	}
+	wh, ok := d.inflight[key]
+	if !ok {
+		wh = &inflightWebhook{}
+		d.inflight[key] = wh
+	}
+	wh.refs++
+	d.mu.Unlock()
+
+	wh.mu.Lock()
+	return func() {
+		wh.mu.Unlock()
+
+		d.mu.Lock()
+		wh.refs--
+		if wh.refs == 0 {
+			delete(d.inflight, key)
+		}
+		d.mu.Unlock()
+	}
+}
+
+func writeStoredResponse(rw http.ResponseWriter, resp StoredResponse) {
+	if resp.ContentType != "" {
+		rw.Header().Set("Content-Type", resp.ContentType)
+	}
+	rw.Header().Set(IdempotencyReplayedHeader, "true")
+	rw.WriteHeader(resp.StatusCode)
+	_, _ = rw.Write(resp.Body)
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// MemoryIdempotencyStore is the in-memory IdempotencyStore which keeps up to the capacity most recently used
+// responses.
+type MemoryIdempotencyStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryIdempotencyItem struct {
+	key  string
+	resp StoredResponse
+}
+
+// NewMemoryIdempotencyStore returns the new MemoryIdempotencyStore with the provided capacity.
+func NewMemoryIdempotencyStore(capacity int) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns the stored response.
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) (StoredResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return StoredResponse{}, false, nil
+	}
+
+	s.order.MoveToFront(el)
+	return el.Value.(*memoryIdempotencyItem).resp, true, nil
+}
+
+// Set stores the response and evicts the least recently used one if the store is full.
+func (s *MemoryIdempotencyStore) Set(_ context.Context, key string, resp StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		el.Value.(*memoryIdempotencyItem).resp = resp
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(&memoryIdempotencyItem{key: key, resp: resp})
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryIdempotencyItem).key)
+	}
+
+	return nil
+}
+
+// FileIdempotencyStore is the IdempotencyStore which keeps every response in a separate file in the directory.
+// Use Prune to remove the old responses.
+type FileIdempotencyStore struct {
+	dir string
+}
+
+// NewFileIdempotencyStore returns the new FileIdempotencyStore. The directory is created if it doesn't exist.
+func NewFileIdempotencyStore(dir string) (*FileIdempotencyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileIdempotencyStore{dir: dir}, nil
+}
+
+// Get returns the stored response.
+func (s *FileIdempotencyStore) Get(_ context.Context, key string) (StoredResponse, bool, error) {
+	var resp StoredResponse
+
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return resp, false, nil
+	}
+	if err != nil {
+		return resp, false, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, false, err
+	}
+
+	return resp, true, nil
+}
+
+// Set stores the response. The file is replaced atomically.
+func (s *FileIdempotencyStore) Set(_ context.Context, key string, resp StoredResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.dir, s.path(key), data)
+}
+
+// Prune removes the responses which were stored earlier than maxAge ago.
+func (s *FileIdempotencyStore) Prune(maxAge time.Duration) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FileIdempotencyStore) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(hash[:])+".json")
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeduplicator_Replay(t *testing.T) {
+	var calls atomic.Int32
+	dedup := &WebhookDeduplicator{Store: NewMemoryIdempotencyStore(10)}
+	handler := dedup.Handler(&WebhookHandler{
+		OnMessageSent: func(context.Context, MessageWebhookData) (TransportResponse, error) {
+			n := calls.Add(1)
+			if n == 1 {
+				return TransportResponse{}, errors.New("temporary error")
+			}
+			return NewSentMessageResponse(fmt.Sprintf("external-%d", n)), nil
+		},
+	})
+
+	body := `{"type":"message_sent","meta":{"id":1,"timestamp":1},"data":{"id":1}}`
+	rec := serveWebhook(handler, http.MethodPost, body)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = serveWebhook(handler, http.MethodPost, body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"external_message_id":"external-2"}`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(IdempotencyReplayedHeader))
+
+	rec = serveWebhook(handler, http.MethodPost, body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"external_message_id":"external-2"}`, rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, int32(2), calls.Load())
+
+	rec = serveWebhook(handler, http.MethodPost, `{"type":"message_sent","meta":{"id":2},"data":{"id":1}}`)
+	assert.JSONEq(t, `{"external_message_id":"external-3"}`, rec.Body.String())
+}
+
+func TestWebhookDeduplicator_TooLarge(t *testing.T) {
+	dedup := &WebhookDeduplicator{Store: NewMemoryIdempotencyStore(10)}
+	handler := dedup.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("handler must not be called")
+	}))
+
+	rec := serveWebhook(handler, http.MethodPost, strings.Repeat(" ", LimitResponse+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestWebhookDeduplicator_Concurrent(t *testing.T) {
+	var calls atomic.Int32
+	dedup := &WebhookDeduplicator{Store: NewMemoryIdempotencyStore(10)}
+	handler := dedup.Handler(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/",
+				strings.NewReader(`{"type":"message_read","meta":{"id":1}}`)))
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, dedup.inflight)
+}
+
+func TestMemoryIdempotencyStore_Evicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore(2)
+
+	require.NoError(t, store.Set(ctx, "a", StoredResponse{StatusCode: 200}))
+	require.NoError(t, store.Set(ctx, "b", StoredResponse{StatusCode: 200}))
+	_, ok, _ := store.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, store.Set(ctx, "c", StoredResponse{StatusCode: 200}))
+
+	_, ok, _ = store.Get(ctx, "b")
+	assert.False(t, ok, "least recently used response must be evicted")
+	_, ok, _ = store.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok, _ = store.Get(ctx, "c")
+	assert.True(t, ok)
+}
+
+func TestFileIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "responses")
+	store, err := NewFileIdempotencyStore(dir)
+	require.NoError(t, err)
+
+	_, ok, err := store.Get(ctx, "message_sent:1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	resp := StoredResponse{StatusCode: 200, ContentType: "application/json", Body: []byte(`{}`)}
+	require.NoError(t, store.Set(ctx, "message_sent:1", resp))
+
+	reopened, err := NewFileIdempotencyStore(dir)
+	require.NoError(t, err)
+	stored, ok, err := reopened.Get(ctx, "message_sent:1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, resp, stored)
+
+	require.NoError(t, store.Prune(time.Hour))
+	_, ok, _ = store.Get(ctx, "message_sent:1")
+	assert.True(t, ok)
+
+	require.NoError(t, store.Prune(-time.Hour))
+	_, ok, _ = store.Get(ctx, "message_sent:1")
+	assert.False(t, ok)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
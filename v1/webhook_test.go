@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWebhookRequest_IsMessageWebhook(t *testing.T) {
@@ -115,3 +116,72 @@ func mustMarshalJSON(v interface{}) []byte {
 	}
 	return data
 }
+
+func TestWebhookRequest_DecodeMessageWebhookData(t *testing.T) {
+	wh := WebhookRequest{
+		Type: MessageSendWebhookType,
+		Data: []byte(`{"id":1,"content":"Hello!","unknown":1}`),
+	}
+
+	data, err := wh.DecodeMessageWebhookData()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), data.ID)
+	assert.Equal(t, "Hello!", data.Content)
+
+	_, err = wh.DecodeMessageWebhookData(DisallowUnknownFields())
+	assert.ErrorContains(t, err, `unknown field "unknown"`)
+
+	_, err = wh.DecodeReactionWebhookData()
+	assert.ErrorIs(t, err, ErrWebhookTypeMismatch)
+
+	_, err = WebhookRequest{Type: MessageReadWebhookType, Data: []byte(`{"id":"1"}`)}.DecodeMessageWebhookData()
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, err, &typeErr)
+
+	_, err = WebhookRequest{Type: MessageReadWebhookType}.DecodeMessageWebhookData()
+	assert.ErrorIs(t, err, ErrWebhookDataMissing)
+}
+
+func TestWebhookRequest_DecodeTemplateWebhookData(t *testing.T) {
+	wh := WebhookRequest{Type: TemplateDeleteWebhookType, Data: []byte(`{"channel_id":1,"code":"code"}`)}
+
+	data, err := wh.DecodeTemplateDeleteWebhookData()
+	require.NoError(t, err)
+	assert.Equal(t, TemplateDeleteWebhookData{ChannelID: 1, Code: "code"}, data)
+
+	_, err = wh.DecodeTemplateCreateWebhookData()
+	assert.ErrorIs(t, err, ErrWebhookTypeMismatch)
+	_, err = wh.DecodeTemplateUpdateWebhookData()
+	assert.ErrorIs(t, err, ErrWebhookTypeMismatch)
+}
+
+func TestDecodeWebhook(t *testing.T) {
+	event, err := DecodeWebhook([]byte(
+		`{"type":"reaction_add","meta":{"id":5,"timestamp":1},"data":{"external_message_id":"ext","new_reaction":"👍"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ReactionAddWebhookType, event.WebhookType())
+	assert.Equal(t, TransportRequestMeta{ID: 5, Timestamp: 1}, event.WebhookMeta())
+
+	reaction, ok := event.(ReactionAddEvent)
+	require.True(t, ok)
+	assert.Equal(t, "👍", reaction.Data.NewReaction)
+
+	event, err = DecodeWebhook([]byte(`{"type":"message_deleted","data":{"id":1}}`))
+	require.NoError(t, err)
+	assert.IsType(t, MessageDeletedEvent{}, event)
+
+	_, err = DecodeWebhook([]byte(`{"type":"unknown","data":{}}`))
+	assert.ErrorIs(t, err, ErrUnknownWebhookType)
+
+	_, err = DecodeWebhook([]byte(`{"type":"message_sent","data":{},"extra":1}`), DisallowUnknownFields())
+	assert.Error(t, err)
+
+	_, err = DecodeWebhook([]byte(`{"type":"message_sent","data":{}} {}`))
+	assert.Error(t, err)
+
+	_, err = DecodeWebhook([]byte(`{"type":"message_sent","data":null}`))
+	assert.ErrorIs(t, err, ErrWebhookDataMissing)
+
+	_, err = DecodeWebhook([]byte(`{"type":"template_delete","meta":{"id":1}}`))
+	assert.ErrorIs(t, err, ErrWebhookDataMissing)
+}
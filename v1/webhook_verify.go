@@ -0,0 +1,285 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWebhookTokenHeader is the default header which contains the shared webhook token.
+	DefaultWebhookTokenHeader = "X-Webhook-Token"
+	// DefaultWebhookSignatureHeader is the default header which contains the webhook body signature.
+	DefaultWebhookSignatureHeader = "X-Webhook-Signature"
+	// DefaultNonceTTL is the time the webhook ID is remembered if the clock skew window is not set.
+	DefaultNonceTTL = 24 * time.Hour
+)
+
+var (
+	// ErrWebhookInvalidToken is returned if the webhook token is missing or invalid.
+	ErrWebhookInvalidToken = errors.New("invalid webhook token")
+	// ErrWebhookInvalidSignature is returned if the webhook signature is missing or invalid.
+	ErrWebhookInvalidSignature = errors.New("invalid webhook signature")
+	// ErrWebhookExpired is returned if the webhook timestamp is outside the allowed clock skew window.
+	ErrWebhookExpired = errors.New("webhook timestamp is outside the allowed window")
+	// ErrWebhookReplayed is returned if the webhook with the same ID was already received.
+	ErrWebhookReplayed = errors.New("webhook was already received")
+	// ErrWebhookTooLarge is returned if the webhook body exceeds LimitResponse.
+	ErrWebhookTooLarge = errors.New("webhook body is too large")
+	// ErrWebhookMalformed is returned if the webhook lacks the fields required by the verification,
+	// e.g. the webhook ID when the replay protection is enabled.
+	ErrWebhookMalformed = errors.New("webhook is malformed")
+	// ErrWebhookVerifierNotConfigured is returned if neither Token nor Secret is set in the WebhookVerifier.
+	ErrWebhookVerifierNotConfigured = errors.New("webhook verifier has neither token nor secret")
+)
+
+// NonceStore remembers the IDs of the received webhooks.
+type NonceStore interface {
+	// Add stores the nonce until expiresAt. It returns false if the nonce is already stored.
+	Add(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	// Remove forgets the nonce, so the webhook with the same ID is accepted again.
+	Remove(ctx context.Context, nonce string) error
+}
+
+// MemoryNonceStore is the in-memory NonceStore. Expired nonces are removed while adding the new ones.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryNonceStore returns the new MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}, now: time.Now}
+}
+
+// Add stores the nonce until expiresAt. It returns false if the nonce is already stored.
+func (s *MemoryNonceStore) Add(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= time.Minute {
+		for key, exp := range s.nonces {
+			if !exp.After(now) {
+				delete(s.nonces, key)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && exp.After(now) {
+		return false, nil
+	}
+
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
+
+// Remove forgets the nonce.
+func (s *MemoryNonceStore) Remove(_ context.Context, nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.nonces, nonce)
+	return nil
+}
+
+// WebhookSignature returns the signature of the webhook body: hex-encoded HMAC-SHA256 of the body.
+func WebhookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookVerifier checks the incoming MessageGateway webhooks. Token or Secret is required, every webhook
+// is rejected with ErrWebhookVerifierNotConfigured otherwise. Other checks are disabled unless they're configured.
+//
+// Example:
+//
+//	verifier := &WebhookVerifier{
+//		Token:        os.Getenv("WEBHOOK_TOKEN"),
+//		MaxClockSkew: 5 * time.Minute,
+//		Nonces:       NewMemoryNonceStore(),
+//	}
+//	http.Handle("/webhook", verifier.Handler(&WebhookHandler{...}))
+//
+// The webhook ID is remembered only if the wrapped handler responds with 2xx status, so MessageGateway
+// can redeliver the webhook which has failed. Redeliveries of the handled webhooks are rejected by the replay
+// protection; use WebhookDeduplicator if they should be answered with the saved response.
+type WebhookVerifier struct {
+	// Token is the shared token which is expected in the TokenHeader.
+	Token string
+	// TokenHeader defaults to DefaultWebhookTokenHeader.
+	TokenHeader string
+	// Secret is the key of the HMAC-SHA256 body signature which is expected in the SignatureHeader.
+	// See WebhookSignature. The signature may be prefixed with "sha256=".
+	Secret []byte
+	// SignatureHeader defaults to DefaultWebhookSignatureHeader.
+	SignatureHeader string
+	// MaxClockSkew is the maximum allowed difference between the webhook timestamp and the current time.
+	MaxClockSkew time.Duration
+	// Nonces is used to reject the webhooks with already seen IDs.
+	Nonces NonceStore
+
+	now func() time.Time
+}
+
+// Handler wraps the handler with the webhook verification. Failed verification is answered with 401 Unauthorized,
+// 409 Conflict for the replayed webhooks, 400 Bad Request for the malformed webhooks, 413 Request Entity Too Large
+// or 500 Internal Server Error if the verifier is not configured. The webhook ID is forgotten if the handler fails
+// or panics.
+func (v *WebhookVerifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := readWebhookBody(req)
+		if err != nil {
+			writeWebhookError(rw, webhookBodyError(err))
+			return
+		}
+
+		nonce, err := v.verify(req.Context(), req.Header, body)
+		if err != nil {
+			writeWebhookError(rw, &WebhookError{StatusCode: verificationStatus(err), Err: err})
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		if nonce == "" {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+		handled := false
+		defer func() {
+			if !handled {
+				_ = v.Nonces.Remove(context.WithoutCancel(req.Context()), nonce)
+			}
+		}()
+
+		next.ServeHTTP(rec, req)
+		handled = rec.status >= http.StatusOK && rec.status < http.StatusMultipleChoices
+	})
+}
+
+// Verify checks the webhook headers and body. The webhook ID is stored in the Nonces, use Nonces.Remove
+// if the webhook handling fails and its redelivery should be accepted.
+func (v *WebhookVerifier) Verify(ctx context.Context, header http.Header, body []byte) error {
+	_, err := v.verify(ctx, header, body)
+	return err
+}
+
+// verify checks the webhook and returns the stored nonce, which is empty if the replay protection is disabled.
+func (v *WebhookVerifier) verify(ctx context.Context, header http.Header, body []byte) (string, error) {
+	if v.Token == "" && len(v.Secret) == 0 {
+		return "", ErrWebhookVerifierNotConfigured
+	}
+
+	if v.Token != "" {
+		token := header.Get(headerOrDefault(v.TokenHeader, DefaultWebhookTokenHeader))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(v.Token)) != 1 {
+			return "", ErrWebhookInvalidToken
+		}
+	}
+
+	if len(v.Secret) > 0 {
+		signature := header.Get(headerOrDefault(v.SignatureHeader, DefaultWebhookSignatureHeader))
+		signature = strings.TrimPrefix(signature, "sha256=")
+		if !hmac.Equal([]byte(signature), []byte(WebhookSignature(v.Secret, body))) {
+			return "", ErrWebhookInvalidSignature
+		}
+	}
+
+	if v.MaxClockSkew <= 0 && v.Nonces == nil {
+		return "", nil
+	}
+
+	var wh struct {
+		Meta TransportRequestMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &wh); err != nil {
+		return "", fmt.Errorf("cannot decode webhook meta: %w", err)
+	}
+
+	return v.checkReplay(ctx, wh.Meta)
+}
+
+func (v *WebhookVerifier) checkReplay(ctx context.Context, meta TransportRequestMeta) (string, error) {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+
+	expiresAt := now.Add(DefaultNonceTTL)
+	if v.MaxClockSkew > 0 {
+		ts := time.Unix(meta.Timestamp, 0)
+		if meta.Timestamp == 0 || ts.Before(now.Add(-v.MaxClockSkew)) || ts.After(now.Add(v.MaxClockSkew)) {
+			return "", ErrWebhookExpired
+		}
+		expiresAt = ts.Add(v.MaxClockSkew)
+	}
+
+	if v.Nonces == nil {
+		return "", nil
+	}
+	if meta.ID == 0 {
+		return "", fmt.Errorf("%w: meta.id is missing", ErrWebhookMalformed)
+	}
+
+	nonce := strconv.FormatUint(meta.ID, 10)
+	added, err := v.Nonces.Add(ctx, nonce, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	if !added {
+		return "", ErrWebhookReplayed
+	}
+
+	return nonce, nil
+}
+
+func verificationStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrWebhookInvalidToken), errors.Is(err, ErrWebhookInvalidSignature),
+		errors.Is(err, ErrWebhookExpired):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrWebhookReplayed):
+		return http.StatusConflict
+	case errors.Is(err, ErrWebhookTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrWebhookMalformed):
+		return http.StatusBadRequest
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+func headerOrDefault(header, def string) string {
+	if header == "" {
+		return def
+	}
+
+	return header
+}
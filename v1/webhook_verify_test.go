@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingNonceStore struct{}
+
+func (failingNonceStore) Add(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("store is unavailable")
+}
+
+func (failingNonceStore) Remove(context.Context, string) error {
+	return errors.New("store is unavailable")
+}
+
+func TestWebhookVerifier_Token(t *testing.T) {
+	v := &WebhookVerifier{Token: "secret"}
+
+	header := http.Header{}
+	assert.ErrorIs(t, v.Verify(context.Background(), header, nil), ErrWebhookInvalidToken)
+
+	header.Set(DefaultWebhookTokenHeader, "secret")
+	assert.NoError(t, v.Verify(context.Background(), header, nil))
+
+	v.TokenHeader = "Authorization"
+	assert.ErrorIs(t, v.Verify(context.Background(), header, nil), ErrWebhookInvalidToken)
+}
+
+func TestWebhookVerifier_Signature(t *testing.T) {
+	v := &WebhookVerifier{Secret: []byte("key")}
+	body := []byte(`{"type":"message_sent"}`)
+
+	header := http.Header{}
+	assert.ErrorIs(t, v.Verify(context.Background(), header, body), ErrWebhookInvalidSignature)
+
+	header.Set(DefaultWebhookSignatureHeader, WebhookSignature([]byte("key"), body))
+	assert.NoError(t, v.Verify(context.Background(), header, body))
+
+	header.Set(DefaultWebhookSignatureHeader, "sha256="+WebhookSignature([]byte("key"), body))
+	assert.NoError(t, v.Verify(context.Background(), header, body))
+
+	header.Set(DefaultWebhookSignatureHeader, WebhookSignature([]byte("other"), body))
+	assert.ErrorIs(t, v.Verify(context.Background(), header, body), ErrWebhookInvalidSignature)
+}
+
+func TestWebhookVerifier_Replay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryNonceStore()
+	store.now = func() time.Time { return now }
+	v := &WebhookVerifier{Token: "secret", MaxClockSkew: time.Minute, Nonces: store, now: func() time.Time { return now }}
+	header := http.Header{DefaultWebhookTokenHeader: {"secret"}}
+
+	assert.NoError(t, v.Verify(context.Background(), header, []byte(`{"meta":{"id":1,"timestamp":1700000030}}`)))
+	assert.ErrorIs(t, v.Verify(context.Background(), header, []byte(`{"meta":{"id":1,"timestamp":1700000030}}`)),
+		ErrWebhookReplayed)
+	assert.ErrorIs(t, v.Verify(context.Background(), header, []byte(`{"meta":{"id":2,"timestamp":1699999900}}`)),
+		ErrWebhookExpired)
+	assert.ErrorIs(t, v.Verify(context.Background(), header, []byte(`{"meta":{"id":3}}`)), ErrWebhookExpired)
+	assert.Error(t, v.Verify(context.Background(), header, []byte(`{"meta":`)))
+	assert.ErrorIs(t, v.Verify(context.Background(), header, []byte(`{"meta":{"timestamp":1700000030}}`)),
+		ErrWebhookMalformed)
+
+	now = now.Add(2 * time.Minute)
+	added, err := store.Add(context.Background(), "1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, added, "expired nonce must be forgotten")
+}
+
+func TestWebhookVerifier_Handler(t *testing.T) {
+	var received string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = string(body)
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	v := &WebhookVerifier{Token: "secret", Nonces: NewMemoryNonceStore()}
+	handler := v.Handler(next)
+	serve := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set(DefaultWebhookTokenHeader, token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := `{"type":"message_read","meta":{"id":1},"data":{}}`
+	assert.Equal(t, http.StatusUnauthorized, serve("invalid", body))
+	assert.Equal(t, http.StatusOK, serve("secret", body))
+	assert.Equal(t, body, received)
+	assert.Equal(t, http.StatusConflict, serve("secret", body))
+	assert.Equal(t, http.StatusBadRequest, serve("secret", "{"))
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusBadRequest, serve("secret", `{"type":"message_read","meta":{},"data":{}}`),
+			"webhooks without ID must not share the nonce")
+	}
+
+	v.Nonces = failingNonceStore{}
+	assert.Equal(t, http.StatusInternalServerError, serve("secret", `{"meta":{"id":2}}`))
+
+	v.Token = ""
+	assert.Equal(t, http.StatusInternalServerError, serve("", body), "verifier without token and secret")
+}
+
+func TestWebhookVerifier_HandlerFailedDelivery(t *testing.T) {
+	var calls int
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			panic("handler failed")
+		default:
+			rw.WriteHeader(http.StatusOK)
+		}
+	})
+
+	handler := (&WebhookVerifier{Token: "secret", Nonces: NewMemoryNonceStore()}).Handler(next)
+	serve := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook",
+			strings.NewReader(`{"type":"message_read","meta":{"id":1},"data":{}}`))
+		req.Header.Set(DefaultWebhookTokenHeader, "secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Panics(t, func() { serve() })
+	assert.Equal(t, http.StatusOK, serve(), "redelivery of the failed webhook must be accepted")
+	assert.Equal(t, http.StatusConflict, serve())
+	assert.Equal(t, 3, calls)
+}